@@ -2,21 +2,217 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/mateimicu/kdiscover/internal/aws"
-	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/mateimicu/kdiscover/internal/cache"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/filter"
 	"github.com/spf13/cobra"
 
 	log "github.com/sirupsen/logrus"
 )
 
 var (
-	awsPartitions []string
-	awsRegions    []string
-	alias         string
+	awsPartitions      []string
+	awsRegions         []string
+	awsIncludeRegions  []string
+	awsExcludeRegions  []string
+	awsEnabledRegions  bool
+	awsProfiles        []string
+	allAWSProfiles     bool
+	awsRoleARNs        []string
+	awsRoleARNsFile    string
+	awsRoleSessionName string
+	awsRoleExternalID  string
+	awsOrganization    bool
+	awsOrgUnits        []string
+	awsOrgRoleTemplate string
+	awsTimeout         time.Duration
+	awsRegionTimeout   time.Duration
+	awsStrict          bool
+	awsConcurrency     int
+	awsRefresh         bool
+	awsCacheTTL        time.Duration
+	awsFilters         []string
+	clusterFilter      filter.Filter
+	alias              string
+	accountAliases     map[string]string
+	contextNames       *cluster.NameTemplate
 )
 
+func getAWSOptions() aws.Options {
+	return aws.Options{
+		Regions:         awsRegions,
+		EnabledRegions:  awsEnabledRegions,
+		Profiles:        awsProfiles,
+		RoleARNs:        awsRoleARNs,
+		RoleSessionName: awsRoleSessionName,
+		RoleExternalID:  awsRoleExternalID,
+
+		Organization:             awsOrganization,
+		OrganizationalUnits:      awsOrgUnits,
+		OrganizationRoleTemplate: awsOrgRoleTemplate,
+
+		RegionTimeout: awsRegionTimeout,
+		Concurrency:   awsConcurrency,
+	}
+}
+
+// discoverEKSClusters searches for clusters within the global timeout.
+// The clusters from the healthy regions are returned together with the
+// failures, an interrupted discovery returns an error. The clusters of
+// the discovery are not filtered, use selectClusters for that. The cache
+// is refreshed when pruning, the clusters missing from a cached region
+// may have been written after the cache was filled.
+func discoverEKSClusters(cmd *cobra.Command, pruning bool) (*aws.Discovery, *aws.DiscoveryError, error) {
+	ctx := cmd.Context()
+	if awsTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, awsTimeout)
+		defer cancel()
+	}
+
+	opts := getAWSOptions()
+	opts.Cache = getCache(awsCacheTTL)
+	opts.Refresh = awsRefresh || pruning
+	opts.Auth = execAuth
+	discovery, err := aws.GetEKSClusters(ctx, opts)
+	// don't use partial results if the discovery was interrupted
+	if err := cmd.Context().Err(); err != nil {
+		return nil, nil, fmt.Errorf("discovery interrupted: %w", err)
+	}
+
+	var discoveryErr *aws.DiscoveryError
+	if errors.As(err, &discoveryErr) {
+		return discovery, discoveryErr, nil
+	}
+	return discovery, nil, err
+}
+
+// selectClusters returns the discovered clusters matching --filter
+func selectClusters(discovery *aws.Discovery) []*cluster.Cluster {
+	return filter.Apply(clusterFilter, discovery.Clusters)
+}
+
+func loadAWSRoles() error {
+	for _, role := range awsRoleARNs {
+		if err := aws.ValidateRoleARN(role); err != nil {
+			return err
+		}
+	}
+	if awsRoleARNsFile != "" {
+		roles, err := aws.ReadRoleARNs(awsRoleARNsFile)
+		if err != nil {
+			return fmt.Errorf("can't read role ARNs from %v: %w", awsRoleARNsFile, err)
+		}
+		awsRoleARNs = append(awsRoleARNs, roles...)
+	}
+
+	log.WithFields(log.Fields{
+		"roles": awsRoleARNs,
+	}).Info("Using roles")
+	return nil
+}
+
+func loadAWSProfiles() error {
+	if allAWSProfiles {
+		var err error
+		awsProfiles, err = aws.GetProfiles()
+		if err != nil {
+			return fmt.Errorf("can't read AWS profiles: %w", err)
+		}
+		if len(awsProfiles) == 0 {
+			return fmt.Errorf("can't find any AWS profiles")
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"profiles": awsProfiles,
+	}).Info("Using profiles")
+	return nil
+}
+
+func loadAWSRegions(ctx context.Context) error {
+	log.WithFields(log.Fields{
+		"partitions": awsPartitions,
+	}).Debug("Search regions for partitions")
+
+	awsRegions = aws.GetRegions(awsPartitions)
+	if awsEnabledRegions {
+		// the regions missing from the static table are found with the
+		// first profile, every identity searches only the regions enabled
+		// for its account
+		profile := ""
+		if len(awsProfiles) != 0 {
+			profile = awsProfiles[0]
+		}
+		for _, region := range aws.GetEnabledRegions(ctx, awsPartitions, profile) {
+			if !slices.Contains(awsRegions, region) {
+				awsRegions = append(awsRegions, region)
+			}
+		}
+	}
+
+	if len(awsRegions) == 0 {
+		log.WithFields(log.Fields{
+			"partitions": awsPartitions,
+		}).Error("Can't find regions for partitions")
+		return fmt.Errorf("can't find regions for partitions %v", awsPartitions)
+	}
+
+	var err error
+	awsRegions, err = aws.FilterRegions(awsRegions, awsIncludeRegions, awsExcludeRegions)
+	if err != nil {
+		return err
+	}
+	if len(awsRegions) == 0 {
+		return fmt.Errorf(
+			"no regions from partitions %v match %v (excluding %v)",
+			awsPartitions, awsIncludeRegions, awsExcludeRegions)
+	}
+
+	log.WithFields(log.Fields{
+		"regions": awsRegions,
+	}).Info("Founds regions")
+	return nil
+}
+
+// loadAWSOptions validates the AWS flags and resolves the profiles,
+// regions and roles to search with
+func loadAWSOptions(ctx context.Context) error {
+	if err := loadAWSProfiles(); err != nil {
+		return err
+	}
+	if err := loadAWSRegions(ctx); err != nil {
+		return err
+	}
+	if err := loadAWSRoles(); err != nil {
+		return err
+	}
+
+	var err error
+	if clusterFilter, err = filter.ParseAll(awsFilters); err != nil {
+		return err
+	}
+
+	if awsConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %v", awsConcurrency)
+	}
+
+	if awsOrganization {
+		if _, err = aws.ParseRoleTemplate(awsOrgRoleTemplate); err != nil {
+			return fmt.Errorf("invalid --organization-role-template: %w", err)
+		}
+	}
+	return nil
+}
+
 func newAWSCommand() *cobra.Command {
 	AWSCommand := &cobra.Command{
 		Use:   "aws",
@@ -35,23 +231,11 @@ func newAWSCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			log.WithFields(log.Fields{
-				"partitions": awsPartitions,
-			}).Debug("Search regions for partitions")
-
-			awsRegions = aws.GetRegions(awsPartitions)
-
-			if len(awsRegions) == 0 {
-				log.WithFields(log.Fields{
-					"partitions": awsPartitions,
-				}).Error("Can't find regions for partitions")
-				return fmt.Errorf("can't find regions for partitions %v", awsPartitions)
+			contextNames, err = cluster.ParseNameTemplate(alias, accountAliases)
+			if err != nil {
+				return fmt.Errorf("invalid --context-name-alias: %w", err)
 			}
-
-			log.WithFields(log.Fields{
-				"regions": awsRegions,
-			}).Info("Founds regions")
-			return nil
+			return loadAWSOptions(cmd.Context())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.HelpFunc()(cmd, args)
@@ -59,22 +243,128 @@ func newAWSCommand() *cobra.Command {
 		},
 	}
 
-	AWSCommand.PersistentFlags().StringSliceVar(
-		&awsPartitions,
-		"aws-partitions",
-		[]string{"aws"},
-		fmt.Sprintf("In what partitions to search for clusters. Supported %v", aws.AllowedParitions()))
-	AWSCommand.PersistentFlags().StringVar(
-		&kubeconfigPath,
-		"kubeconfig-path",
-		kubeconfig.GetDefaultKubeconfigPath(),
-		"Path to the kubeconfig to work with")
+	addAWSFlags(AWSCommand)
 	AWSCommand.PersistentFlags().StringVar(
 		&alias,
 		"context-name-alias",
 		"{{.Name}}",
-		"Template for the context name. Has acces to Cluster type")
+		"Template for the context name. Has acces to Cluster type and to the functions lower, upper, replace, "+
+			"regexReplace, regexFind, trimPrefix, trimSuffix, truncate, default, tag and accountAlias")
+	AWSCommand.PersistentFlags().StringToStringVar(
+		&accountAliases,
+		"account-alias",
+		map[string]string{},
+		"Names returned by accountAlias in --context-name-alias, as ACCOUNT_ID=NAME. "+
+			"The other accounts use the account name or ID")
 
 	AWSCommand.AddCommand(newListCommand(), newUpdateCommand())
 	return AWSCommand
 }
+
+// addAWSFlags adds the flags describing where to search for clusters
+func addAWSFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(
+		&awsPartitions,
+		"aws-partitions",
+		[]string{"aws"},
+		fmt.Sprintf("In what partitions to search for clusters. Supported %v", aws.AllowedParitions()))
+	cmd.PersistentFlags().StringSliceVar(
+		&awsIncludeRegions,
+		"aws-regions",
+		[]string{},
+		"Search only the regions matching this patterns (ex: eu-*,us-east-1). By default all regions are searched")
+	cmd.PersistentFlags().StringSliceVar(
+		&awsExcludeRegions,
+		"aws-exclude-regions",
+		[]string{},
+		"Do not search the regions matching this patterns (ex: ap-*)")
+	cmd.PersistentFlags().BoolVar(
+		&awsEnabledRegions,
+		"aws-enabled-regions",
+		false,
+		"Search only the regions enabled for the account (EC2 DescribeRegions) instead of all the known regions")
+	cmd.PersistentFlags().StringSliceVar(
+		&awsProfiles,
+		"aws-profiles",
+		[]string{},
+		"Named AWS profiles to search for clusters. By default the default credential chain is used")
+	cmd.PersistentFlags().BoolVar(
+		&allAWSProfiles,
+		"aws-all-profiles",
+		false,
+		"Search for clusters with every profile defined in the shared AWS config files")
+	cmd.MarkFlagsMutuallyExclusive("aws-profiles", "aws-all-profiles")
+	cmd.PersistentFlags().StringSliceVar(
+		&awsRoleARNs,
+		"aws-role-arns",
+		[]string{},
+		"IAM roles to assume with every profile in order to search for clusters")
+	cmd.PersistentFlags().StringVar(
+		&awsRoleARNsFile,
+		"aws-role-arns-file",
+		"",
+		"File with IAM roles to assume, one role ARN per line")
+	cmd.PersistentFlags().StringVar(
+		&awsRoleSessionName,
+		"aws-role-session-name",
+		"kdiscover",
+		"Session name used when assuming roles")
+	cmd.PersistentFlags().StringVar(
+		&awsRoleExternalID,
+		"aws-role-external-id",
+		"",
+		"External ID used when assuming roles")
+	cmd.PersistentFlags().BoolVar(
+		&awsOrganization,
+		"organization",
+		false,
+		"Search for clusters in all the accounts of the AWS Organization, requires access to the management account")
+	cmd.PersistentFlags().StringSliceVar(
+		&awsOrgUnits,
+		"organization-ous",
+		[]string{},
+		"Restrict the organization accounts to the given organizational units (and their children)")
+	cmd.PersistentFlags().StringVar(
+		&awsOrgRoleTemplate,
+		"organization-role-template",
+		"",
+		"Role assumed in every organization account, required by --organization. Either a role name or "+
+			"a template for the role ARN with access to AccountID, AccountName and Partition")
+	cmd.PersistentFlags().DurationVar(
+		&awsTimeout,
+		"timeout",
+		0,
+		"Maximum time for the whole discovery (ex: 2m), regions still searched are reported as failed. Zero means no limit")
+	cmd.PersistentFlags().DurationVar(
+		&awsRegionTimeout,
+		"region-timeout",
+		0,
+		"Maximum time spent searching one region (ex: 30s), slower regions are reported as failed. Zero means no limit")
+	cmd.PersistentFlags().IntVar(
+		&awsConcurrency,
+		"concurrency",
+		aws.DefaultConcurrency,
+		"Maximum number of clusters described in parallel across all the regions")
+	cmd.PersistentFlags().StringArrayVar(
+		&awsFilters,
+		"filter",
+		[]string{},
+		fmt.Sprintf("Select the clusters matching the expression (ex: 'tag:env=prod AND version>=1.28'), "+
+			"can be repeated. Operators =, !=, ~, !~, >=, <=, >, < on tag:<key> or %v",
+			strings.Join(filter.Fields(), ", ")))
+	cmd.PersistentFlags().BoolVar(
+		&awsRefresh,
+		"refresh",
+		false,
+		"Ignore the cached clusters and search all the regions again")
+	cmd.PersistentFlags().DurationVar(
+		&awsCacheTTL,
+		"cache-ttl",
+		cache.DefaultTTL,
+		"How long the discovered clusters are cached. Zero disables the cache")
+	cmd.PersistentFlags().BoolVar(
+		&awsStrict,
+		"strict",
+		false,
+		"Exit with an error if some regions or clusters could not be searched")
+}
@@ -0,0 +1,54 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/spf13/cobra"
+)
+
+func getFailuresTable(failures *aws.DiscoveryError) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Region", "Identity", "Cluster", "Cause", "Error"})
+	rows := []table.Row{}
+	for _, f := range failures.Failures {
+		region, cls := f.Region, f.Cluster
+		if region == "" {
+			// the identity failed before searching any region
+			region = "-"
+		}
+		if cls == "" {
+			cls = "-"
+		}
+		rows = append(rows, table.Row{region, f.Identity.String(), cls, f.Cause(), f.Err.Error()})
+	}
+	tw.AppendRows(rows)
+
+	tw.AppendFooter(table.Row{"", "", "", "Number of failures", len(failures.Failures)})
+
+	tw.SortBy([]table.SortBy{{Name: "Region", Mode: table.Asc}, {Name: "Cluster", Mode: table.Asc}})
+
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Format.Header = text.FormatLower
+	tw.Style().Format.Footer = text.FormatLower
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+// reportFailures prints the regions and clusters that could not be searched,
+// with --strict any failure is returned as an error
+func reportFailures(cmd *cobra.Command, failures *aws.DiscoveryError) error {
+	if failures == nil || len(failures.Failures) == 0 {
+		return nil
+	}
+
+	cmd.Println("Partial results, some regions or clusters could not be searched:")
+	cmd.Println(getFailuresTable(failures))
+	if awsStrict {
+		return fmt.Errorf("discovery incomplete: %v failures", len(failures.Failures))
+	}
+	return nil
+}
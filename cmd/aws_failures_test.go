@@ -0,0 +1,73 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func getMockFailures() *aws.DiscoveryError {
+	return &aws.DiscoveryError{Failures: []*aws.Failure{
+		{Region: "eu-west-1", Err: context.DeadlineExceeded},
+		{Region: "us-east-1", Cluster: "broken", Identity: aws.Identity{Profile: "dev"}, Err: errors.New("boom")},
+		{Identity: aws.Identity{Profile: "missing"}, Err: errors.New("failed to get shared config profile")},
+	}}
+}
+
+func TestGetFailuresTable(t *testing.T) {
+	t.Parallel()
+	failures := getMockFailures()
+	table := getFailuresTable(failures)
+
+	for _, f := range failures.Failures {
+		assert.Contains(t, table, f.Region)
+		assert.Contains(t, table, string(f.Cause()))
+	}
+	assert.Contains(t, table, "broken")
+	assert.Contains(t, table, "profile dev")
+	assert.Contains(t, table, "profile missing")
+}
+
+func TestReportFailures(t *testing.T) {
+	tts := []struct {
+		Failures    *aws.DiscoveryError
+		Strict      bool
+		ExpectError bool
+	}{
+		{nil, false, false},
+		{nil, true, false},
+		{getMockFailures(), false, false},
+		{getMockFailures(), true, true},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("failures %v strict %v", tt.Failures != nil, tt.Strict)
+		t.Run(testname, func(t *testing.T) {
+			awsStrict = tt.Strict
+			defer func() { awsStrict = false }()
+
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+
+			err := reportFailures(cmd, tt.Failures)
+			if tt.ExpectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.Failures != nil {
+				assert.Contains(t, out.String(), "Partial results")
+			} else {
+				assert.Empty(t, out.String())
+			}
+		})
+	}
+}
@@ -2,9 +2,12 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/jedib0t/go-pretty/text"
-	"github.com/mateimicu/kdiscover/internal/aws"
 	"github.com/mateimicu/kdiscover/internal/cluster"
 	"github.com/mateimicu/kdiscover/internal/kubeconfig"
 	log "github.com/sirupsen/logrus"
@@ -16,7 +19,45 @@ type clusterDescribe interface {
 	GetName() string
 	GetRegion() string
 	GetStatus() string
-	PrettyName(templateValue string) (string, error)
+	GetAccountID() string
+	GetProfile() string
+	GetTags() map[string]string
+	GetKubernetesVersion() string
+	GetPlatformVersion() string
+	GetCreatedAt() time.Time
+	GetVpcID() string
+	GetEndpointAccess() string
+	PrettyName(names *cluster.NameTemplate) (string, error)
+}
+
+const (
+	tableOutput = "table"
+	wideOutput  = "wide"
+)
+
+var (
+	listOutput    string
+	listColumns   []string
+	listSortBy    string
+	listNoHeaders bool
+	listNoFooter  bool
+)
+
+// getTableOptions returns the columns chosen with --columns or
+// the ones for the output format
+func getTableOptions() (tableOptions, error) {
+	names := listColumns
+	if len(names) == 0 {
+		names = defaultColumns
+		if listOutput == wideOutput {
+			names = wideColumns
+		}
+	}
+	columns, err := lookupColumns(names)
+	if err != nil {
+		return tableOptions{}, err
+	}
+	return tableOptions{Columns: columns, NoHeaders: listNoHeaders, NoFooter: listNoFooter}, nil
 }
 
 type exportable interface {
@@ -38,59 +79,148 @@ func convertToInterfaces(clusters []*cluster.Cluster) []clusterDescribe {
 	return cls
 }
 
-func getTable(clusters []clusterDescribe, e exportable, alias string) string {
+// contextName renders the context name template, on errors it falls back on
+// the cluster name
+func contextName(cls clusterDescribe, names *cluster.NameTemplate) string {
+	name, err := cls.PrettyName(names)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err":          err.Error(),
+			"cluster-name": cls.GetName(),
+		}).Warn("Failback on name")
+		return cls.GetName()
+	}
+	return name
+}
+
+type tableOptions struct {
+	Columns   []tableColumn
+	NoHeaders bool
+	NoFooter  bool
+}
+
+func getTable(clusters []clusterDescribe, e exportable, names *cluster.NameTemplate, opts tableOptions) string {
 	tw := table.NewWriter()
-	tw.AppendHeader(table.Row{"Cluster Name", "Region", "Status", "Exported Locally"})
+	header := table.Row{}
+	configs := []table.ColumnConfig{}
+	for i, c := range opts.Columns {
+		header = append(header, c.Header)
+		if c.Header == tableColumns["exported"].Header {
+			configs = append(configs, table.ColumnConfig{
+				Number:      i + 1,
+				Align:       text.AlignCenter,
+				AlignHeader: text.AlignCenter,
+			})
+		}
+	}
+	if !opts.NoHeaders {
+		tw.AppendHeader(header)
+	}
+
 	rows := []table.Row{}
 	for _, cls := range clusters {
-		name, err := cls.PrettyName(alias)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"err":          err.Error(),
-				"cluster-name": cls.GetName(),
-			}).Warn("Failback on name")
-			name = cls.GetName()
+		row := table.Row{}
+		for _, c := range opts.Columns {
+			row = append(row, c.Value(cls, e, names))
 		}
-		rows = append(rows, table.Row{name, cls.GetRegion(), cls.GetStatus(), getExportedString(e, cls)})
+		rows = append(rows, row)
 	}
 	tw.AppendRows(rows)
 
-	tw.AppendFooter(table.Row{"", "Number of clusters", len(clusters)})
+	if !opts.NoFooter {
+		footer := make(table.Row, max(len(opts.Columns), 2))
+		footer[len(footer)-2] = "Number of clusters"
+		footer[len(footer)-1] = len(clusters)
+		tw.AppendFooter(footer)
+	}
 
-	tw.SetAutoIndex(true)
-	tw.SortBy([]table.SortBy{{Name: "Region", Mode: table.Dsc}})
+	tw.SetAutoIndex(!opts.NoHeaders)
 
 	tw.SetStyle(table.StyleLight)
 	tw.Style().Format.Header = text.FormatLower
 	tw.Style().Format.Footer = text.FormatLower
 	tw.Style().Options.SeparateColumns = false
-	tw.SetColumnConfigs([]table.ColumnConfig{
-		{
-			Name:        "Exported Locally",
-			Align:       text.AlignCenter,
-			AlignHeader: text.AlignCenter,
-		},
-	})
+	tw.SetColumnConfigs(configs)
 	// render it
 	return tw.Render()
 }
 
+func formatCreatedAt(createdAt time.Time) string {
+	if createdAt.IsZero() {
+		return ""
+	}
+	return createdAt.Format(time.DateOnly)
+}
+
+// printDataAge tells if some of the clusters come from the cache
+func printDataAge(cmd *cobra.Command, clusters []*cluster.Cluster, start time.Time) {
+	oldest := start
+	for _, cls := range clusters {
+		if cls.DiscoveredAt.Before(oldest) {
+			oldest = cls.DiscoveredAt
+		}
+	}
+	if oldest.Before(start) {
+		cmd.Printf("Cached data from %v ago, use --refresh to search again\n", start.Sub(oldest).Round(time.Second))
+	}
+}
+
 func newListCommand() *cobra.Command {
 	listCommand := &cobra.Command{
 		Use:   "list",
 		Short: "List all EKS Clusters",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			remoteEKSClusters := aws.GetEKSClusters(awsRegions)
-			log.Info(remoteEKSClusters)
-			k, err := kubeconfig.LoadKubeconfig(kubeconfigPath)
+			printer, err := newListPrinter(listOutput, listNoHeaders)
+			if err != nil {
+				return err
+			}
+			tableOpts, err := getTableOptions()
+			if err != nil {
+				return err
+			}
+			sortBy, err := parseSortKey(listSortBy)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			discovery, failures, err := discoverEKSClusters(cmd, false)
+			if err != nil {
+				return err
+			}
+			remoteEKSClusters := selectClusters(discovery)
+			// the clusters are exported if kubectl can use them
+			k, err := kubeconfig.LoadMerged(kubeconfigPaths)
 			if err != nil {
 				return err
 			}
 
-			cmd.Println(getTable(convertToInterfaces(remoteEKSClusters), k, alias))
-			return nil
+			sortClusters(remoteEKSClusters, sortBy, k, contextNames)
+			if printer != nil {
+				err = printer(cmd.OutOrStdout(), newClusterList(remoteEKSClusters, k, contextNames))
+			} else {
+				cmd.Println(getTable(convertToInterfaces(remoteEKSClusters), k, contextNames, tableOpts))
+			}
+			if err != nil {
+				return err
+			}
+			printDataAge(cmd, remoteEKSClusters, start)
+			return reportFailures(cmd, failures)
 		},
 	}
 
+	listCommand.Flags().StringVarP(
+		&listOutput, "output", "o", tableOutput,
+		fmt.Sprintf("Output format. One of %v. The machine readable formats use the %v schema",
+			strings.Join(listOutputFormats, ", "), listAPIVersion))
+	listCommand.Flags().StringSliceVar(
+		&listColumns, "columns", []string{},
+		fmt.Sprintf("Columns of the table (default %v). One of %v or %v<key>",
+			strings.Join(defaultColumns, ","), strings.Join(columnNames(), ", "), tagColumnPrefix))
+	listCommand.Flags().StringVar(
+		&listSortBy, "sort-by", "region:desc",
+		"Sort the clusters by a column, optionally followed by :asc or :desc")
+	listCommand.Flags().BoolVar(&listNoHeaders, "no-headers", false, "Don't print the table and CSV headers")
+	listCommand.Flags().BoolVar(&listNoFooter, "no-footer", false, "Don't print the table footer")
+
 	return listCommand
 }
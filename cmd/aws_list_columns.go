@@ -0,0 +1,159 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/filter"
+)
+
+const (
+	tagColumnPrefix = "tags."
+	ascending       = "asc"
+	descending      = "desc"
+)
+
+var (
+	defaultColumns = []string{"context", "region", "status", "exported"}
+	wideColumns    = append(append([]string{}, defaultColumns...), "version", "platform", "access", "vpc", "created")
+)
+
+type columnValue func(cls clusterDescribe, e exportable, names *cluster.NameTemplate) string
+
+type tableColumn struct {
+	Header string
+	Value  columnValue
+	// Compare orders the values when sorting, by default they are
+	// compared as strings
+	Compare func(a, b string) int
+}
+
+var tableColumns = map[string]tableColumn{
+	"context": {Header: "Context", Value: func(cls clusterDescribe, _ exportable, names *cluster.NameTemplate) string {
+		return contextName(cls, names)
+	}},
+	"name": {Header: "Name", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetName()
+	}},
+	"region": {Header: "Region", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetRegion()
+	}},
+	"status": {Header: "Status", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetStatus()
+	}},
+	"endpoint": {Header: "Endpoint", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetEndpoint()
+	}},
+	"account": {Header: "Account", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetAccountID()
+	}},
+	"profile": {Header: "Profile", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetProfile()
+	}},
+	"exported": {Header: "Exported Locally", Value: func(
+		cls clusterDescribe, e exportable, _ *cluster.NameTemplate,
+	) string {
+		return getExportedString(e, cls)
+	}},
+	"version": {Header: "Version", Compare: filter.CompareVersions, Value: func(
+		cls clusterDescribe, _ exportable, _ *cluster.NameTemplate,
+	) string {
+		return cls.GetKubernetesVersion()
+	}},
+	"platform": {Header: "Platform", Compare: filter.CompareVersions, Value: func(
+		cls clusterDescribe, _ exportable, _ *cluster.NameTemplate,
+	) string {
+		return cls.GetPlatformVersion()
+	}},
+	"access": {Header: "Endpoint Access", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetEndpointAccess()
+	}},
+	"vpc": {Header: "VPC", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return cls.GetVpcID()
+	}},
+	"created": {Header: "Created", Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+		return formatCreatedAt(cls.GetCreatedAt())
+	}},
+}
+
+// columnNames returns the known columns, besides tags.<key>
+func columnNames() []string {
+	names := make([]string, 0, len(tableColumns))
+	for name := range tableColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupColumn(name string) (tableColumn, error) {
+	if key, ok := strings.CutPrefix(name, tagColumnPrefix); ok && key != "" {
+		return tableColumn{
+			Header: "Tag " + key,
+			Value: func(cls clusterDescribe, _ exportable, _ *cluster.NameTemplate) string {
+				return cls.GetTags()[key]
+			},
+		}, nil
+	}
+	c, ok := tableColumns[strings.ToLower(name)]
+	if !ok {
+		return tableColumn{}, fmt.Errorf("unknown column %q, use %v<key> or one of %v",
+			name, tagColumnPrefix, strings.Join(columnNames(), ", "))
+	}
+	return c, nil
+}
+
+func lookupColumns(names []string) ([]tableColumn, error) {
+	columns := make([]tableColumn, 0, len(names))
+	for _, name := range names {
+		c, err := lookupColumn(name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// sortKey is a column and a direction parsed from `column[:asc|desc]`
+type sortKey struct {
+	Column     tableColumn
+	Descending bool
+}
+
+func parseSortKey(value string) (*sortKey, error) {
+	name, direction, _ := strings.Cut(value, ":")
+	c, err := lookupColumn(name)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(direction) {
+	case "", ascending:
+		return &sortKey{Column: c}, nil
+	case descending:
+		return &sortKey{Column: c, Descending: true}, nil
+	}
+	return nil, fmt.Errorf("unknown sort direction %q, use %v or %v", direction, ascending, descending)
+}
+
+// sortClusters orders the clusters in place, equal values keep their order
+func sortClusters(clusters []*cluster.Cluster, key *sortKey, e exportable, names *cluster.NameTemplate) {
+	compare := key.Column.Compare
+	if compare == nil {
+		compare = strings.Compare
+	}
+	values := make(map[*cluster.Cluster]string, len(clusters))
+	for _, cls := range clusters {
+		values[cls] = key.Column.Value(cls, e, names)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		order := compare(values[clusters[i]], values[clusters[j]])
+		if key.Descending {
+			return order > 0
+		}
+		return order < 0
+	})
+}
@@ -0,0 +1,128 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func defaultTableOptions(t *testing.T) tableOptions {
+	t.Helper()
+	columns, err := lookupColumns(defaultColumns)
+	assert.NoError(t, err)
+	return tableOptions{Columns: columns}
+}
+
+func TestLookupColumns(t *testing.T) {
+	t.Parallel()
+	for _, names := range [][]string{
+		defaultColumns, wideColumns, columnNames(), {"tags.env", "NAME"},
+	} {
+		columns, err := lookupColumns(names)
+		assert.NoError(t, err, names)
+		assert.Len(t, columns, len(names))
+	}
+
+	for _, name := range []string{"unknown", "tags.", ""} {
+		_, err := lookupColumn(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGetTableColumns(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetMockClusters(3)
+	columns, err := lookupColumns([]string{"name", "account", "tags.env"})
+	assert.NoError(t, err)
+
+	names := mustNameTemplate(t, "{{.Name}}")
+	r := getTable(convertToInterfaces(clusters), mockExportable{}, names, tableOptions{Columns: columns})
+	assert.Contains(t, r, "tag env")
+	assert.NotContains(t, r, "exported locally")
+	for _, cls := range clusters {
+		assert.Contains(t, r, cls.Name)
+		assert.Contains(t, r, cls.AccountID)
+		assert.Contains(t, r, cls.Tags["env"])
+		assert.NotContains(t, r, cls.Region)
+	}
+}
+
+func TestGetTableNoHeadersNoFooter(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetMockClusters(2)
+	opts := defaultTableOptions(t)
+	opts.NoHeaders = true
+	opts.NoFooter = true
+
+	r := getTable(convertToInterfaces(clusters), mockExportable{}, mustNameTemplate(t, "{{.Name}}"), opts)
+	assert.NotContains(t, r, "context")
+	assert.NotContains(t, r, "number of clusters")
+	// the borders and one line for each cluster
+	assert.Len(t, strings.Split(strings.TrimSpace(r), "\n"), len(clusters)+2)
+	for _, cls := range clusters {
+		assert.Contains(t, r, cls.Name)
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		value      string
+		descending bool
+	}{
+		{"name", false},
+		{"name:asc", false},
+		{"version:desc", true},
+		{"tags.env:DESC", true},
+	}
+	for _, tt := range tts {
+		key, err := parseSortKey(tt.value)
+		if assert.NoError(t, err, tt.value) {
+			assert.Equal(t, tt.descending, key.Descending, tt.value)
+		}
+	}
+
+	for _, value := range []string{"", "unknown", "name:up", "tags.:asc"} {
+		_, err := parseSortKey(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSortClusters(t *testing.T) {
+	t.Parallel()
+	versions := []string{"1.9", "1.30", "1.28", ""}
+	clusters := make([]*cluster.Cluster, 0, len(versions))
+	for i, v := range versions {
+		cls := cluster.NewCluster()
+		cls.Name = fmt.Sprintf("cluster-%v", i)
+		cls.KubernetesVersion = v
+		clusters = append(clusters, cls)
+	}
+
+	sortedVersions := func() []string {
+		r := []string{}
+		for _, cls := range clusters {
+			r = append(r, cls.KubernetesVersion)
+		}
+		return r
+	}
+
+	key, err := parseSortKey("version")
+	assert.NoError(t, err)
+	sortClusters(clusters, key, mockExportable{}, mustNameTemplate(t, "{{.Name}}"))
+	assert.Equal(t, []string{"", "1.9", "1.28", "1.30"}, sortedVersions())
+
+	key, err = parseSortKey("version:desc")
+	assert.NoError(t, err)
+	sortClusters(clusters, key, mockExportable{}, mustNameTemplate(t, "{{.Name}}"))
+	assert.Equal(t, []string{"1.30", "1.28", "1.9", ""}, sortedVersions())
+
+	key, err = parseSortKey("name:desc")
+	assert.NoError(t, err)
+	sortClusters(clusters, key, mockExportable{}, mustNameTemplate(t, "{{.Name}}"))
+	assert.Equal(t, "cluster-3", clusters[0].Name)
+}
@@ -0,0 +1,235 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	jsonOutput       = "json"
+	yamlOutput       = "yaml"
+	csvOutput        = "csv"
+	tsvOutput        = "tsv"
+	nameOutput       = "name"
+	jsonPathPrefix   = "jsonpath="
+	goTemplatePrefix = "go-template="
+
+	// listAPIVersion must change when fields are renamed or removed,
+	// new fields can be added without changing it
+	listAPIVersion = "kdiscover/v1"
+	listKind       = "ClusterList"
+)
+
+var listOutputFormats = []string{
+	tableOutput, wideOutput, jsonOutput, yamlOutput, csvOutput, tsvOutput, nameOutput,
+	jsonPathPrefix + "...", goTemplatePrefix + "...",
+}
+
+// clusterList is the stable schema of the machine readable outputs
+type clusterList struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Items      []clusterItem `json:"items"`
+}
+
+type clusterItem struct {
+	ID                    string            `json:"id"`
+	Name                  string            `json:"name"`
+	ContextName           string            `json:"contextName"`
+	Region                string            `json:"region"`
+	Status                string            `json:"status"`
+	Exported              bool              `json:"exported"`
+	ARN                   string            `json:"arn,omitempty"`
+	Endpoint              string            `json:"endpoint"`
+	AccountID             string            `json:"accountID,omitempty"`
+	AccountName           string            `json:"accountName,omitempty"`
+	Profile               string            `json:"profile,omitempty"`
+	RoleARN               string            `json:"roleARN,omitempty"`
+	KubernetesVersion     string            `json:"kubernetesVersion,omitempty"`
+	PlatformVersion       string            `json:"platformVersion,omitempty"`
+	Tags                  map[string]string `json:"tags,omitempty"`
+	CreatedAt             *time.Time        `json:"createdAt,omitempty"`
+	EndpointPublicAccess  bool              `json:"endpointPublicAccess"`
+	EndpointPrivateAccess bool              `json:"endpointPrivateAccess"`
+	VpcID                 string            `json:"vpcID,omitempty"`
+	OIDCIssuer            string            `json:"oidcIssuer,omitempty"`
+	DiscoveredAt          *time.Time        `json:"discoveredAt,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newClusterList(clusters []*cluster.Cluster, e exportable, names *cluster.NameTemplate) clusterList {
+	items := make([]clusterItem, 0, len(clusters))
+	for _, cls := range clusters {
+		items = append(items, clusterItem{
+			ID:                    cls.GetUniqueID(),
+			Name:                  cls.Name,
+			ContextName:           contextName(cls, names),
+			Region:                cls.Region,
+			Status:                cls.Status,
+			Exported:              e.IsExported(cls),
+			ARN:                   cls.ARN,
+			Endpoint:              cls.Endpoint,
+			AccountID:             cls.AccountID,
+			AccountName:           cls.AccountName,
+			Profile:               cls.Profile,
+			RoleARN:               cls.RoleARN,
+			KubernetesVersion:     cls.KubernetesVersion,
+			PlatformVersion:       cls.PlatformVersion,
+			Tags:                  cls.Tags,
+			CreatedAt:             optionalTime(cls.CreatedAt),
+			EndpointPublicAccess:  cls.EndpointPublicAccess,
+			EndpointPrivateAccess: cls.EndpointPrivateAccess,
+			VpcID:                 cls.VpcID,
+			OIDCIssuer:            cls.OIDCIssuer,
+			DiscoveredAt:          optionalTime(cls.DiscoveredAt),
+		})
+	}
+	return clusterList{APIVersion: listAPIVersion, Kind: listKind, Items: items}
+}
+
+// listPrinter writes the clusters in a machine readable format
+type listPrinter func(w io.Writer, list clusterList) error
+
+// newListPrinter validates the format before the discovery starts,
+// nil is returned for the table formats
+func newListPrinter(format string, noHeaders bool) (listPrinter, error) {
+	switch format {
+	case tableOutput, wideOutput:
+		return nil, nil
+	case jsonOutput:
+		return printJSON, nil
+	case yamlOutput:
+		return printYAML, nil
+	case csvOutput:
+		return newDelimitedPrinter(',', noHeaders), nil
+	case tsvOutput:
+		return newDelimitedPrinter('\t', noHeaders), nil
+	case nameOutput:
+		return printNames, nil
+	}
+
+	if expr, ok := strings.CutPrefix(format, jsonPathPrefix); ok {
+		return newJSONPathPrinter(expr)
+	}
+	if text, ok := strings.CutPrefix(format, goTemplatePrefix); ok {
+		return newTemplatePrinter(text)
+	}
+	return nil, fmt.Errorf("unknown output format %q, use one of %v", format, strings.Join(listOutputFormats, ", "))
+}
+
+func printJSON(w io.Writer, list clusterList) error {
+	data, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func printYAML(w io.Writer, list clusterList) error {
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+var delimitedHeader = []string{
+	"id", "contextName", "name", "region", "status", "exported", "accountID", "kubernetesVersion",
+}
+
+func newDelimitedPrinter(separator rune, noHeaders bool) listPrinter {
+	return func(w io.Writer, list clusterList) error {
+		cw := csv.NewWriter(w)
+		cw.Comma = separator
+		if !noHeaders {
+			if err := cw.Write(delimitedHeader); err != nil {
+				return err
+			}
+		}
+		for _, item := range list.Items {
+			err := cw.Write([]string{
+				item.ID, item.ContextName, item.Name, item.Region, item.Status,
+				strconv.FormatBool(item.Exported), item.AccountID, item.KubernetesVersion,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+func printNames(w io.Writer, list clusterList) error {
+	for _, item := range list.Items {
+		if _, err := fmt.Fprintln(w, item.ContextName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toGeneric converts the list to maps and slices, the way kubectl does,
+// so the templates use the JSON field names
+func toGeneric(list clusterList) (interface{}, error) {
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+func newJSONPathPrinter(expr string) (listPrinter, error) {
+	jp := jsonpath.New("output")
+	if err := jp.Parse(expr); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath %q: %w", expr, err)
+	}
+	return func(w io.Writer, list clusterList) error {
+		generic, err := toGeneric(list)
+		if err != nil {
+			return err
+		}
+		if err := jp.Execute(w, generic); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	}, nil
+}
+
+func newTemplatePrinter(text string) (listPrinter, error) {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %w", err)
+	}
+	return func(w io.Writer, list clusterList) error {
+		generic, err := toGeneric(list)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(w, generic)
+	}, nil
+}
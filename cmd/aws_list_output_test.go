@@ -0,0 +1,104 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func renderList(t *testing.T, format string, clusters []*cluster.Cluster) string {
+	t.Helper()
+	printer, err := newListPrinter(format, false)
+	if !assert.NoError(t, err) || !assert.NotNil(t, printer) {
+		return ""
+	}
+	var out bytes.Buffer
+	assert.NoError(t, printer(&out, newClusterList(clusters, mockExportable{}, mustNameTemplate(t, "ctx-{{.Name}}"))))
+	return out.String()
+}
+
+func TestListPrinterFormats(t *testing.T) {
+	t.Parallel()
+	valid := []string{
+		"table", "wide", "json", "yaml", "csv", "tsv", "name",
+		"jsonpath={.items[*].id}", "go-template={{range .items}}{{.name}}{{end}}",
+	}
+	for _, format := range valid {
+		_, err := newListPrinter(format, false)
+		assert.NoError(t, err, format)
+	}
+
+	invalid := []string{"xml", "jsonpath={.items[", "go-template={{.name"}
+	for _, format := range invalid {
+		_, err := newListPrinter(format, false)
+		assert.Error(t, err, format)
+	}
+}
+
+func TestListOutputJSONYAML(t *testing.T) {
+	t.Parallel()
+	for _, format := range []string{jsonOutput, yamlOutput} {
+		for _, tt := range tableCases {
+			testname := fmt.Sprintf("%v with %v clusters", format, len(tt.Clusters))
+			t.Run(testname, func(t *testing.T) {
+				out := renderList(t, format, tt.Clusters)
+
+				var list clusterList
+				if format == jsonOutput {
+					assert.NoError(t, json.Unmarshal([]byte(out), &list))
+				} else {
+					assert.NoError(t, yaml.Unmarshal([]byte(out), &list))
+				}
+				assert.Equal(t, listAPIVersion, list.APIVersion)
+				assert.Equal(t, listKind, list.Kind)
+				assert.Len(t, list.Items, len(tt.Clusters))
+				for i, cls := range tt.Clusters {
+					assert.Equal(t, cls.GetUniqueID(), list.Items[i].ID)
+					assert.Equal(t, "ctx-"+cls.Name, list.Items[i].ContextName)
+					assert.Equal(t, cls.Region, list.Items[i].Region)
+					assert.Equal(t, cls.Status, list.Items[i].Status)
+					assert.False(t, list.Items[i].Exported)
+				}
+			})
+		}
+	}
+}
+
+func TestListOutputDelimited(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetMockClusters(3)
+	for format, separator := range map[string]rune{csvOutput: ',', tsvOutput: '\t'} {
+		r := csv.NewReader(strings.NewReader(renderList(t, format, clusters)))
+		r.Comma = separator
+		records, err := r.ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, len(clusters)+1, format) {
+			assert.Equal(t, delimitedHeader, records[0])
+			assert.Equal(t, clusters[1].GetUniqueID(), records[2][0])
+			assert.Equal(t, "false", records[2][5])
+		}
+	}
+}
+
+func TestListOutputTemplates(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetMockClusters(2)
+
+	assert.Equal(t,
+		fmt.Sprintf("ctx-%v\nctx-%v\n", clusters[0].Name, clusters[1].Name),
+		renderList(t, nameOutput, clusters))
+	assert.Equal(t,
+		fmt.Sprintf("%v %v\n", clusters[0].Region, clusters[1].Region),
+		renderList(t, "jsonpath={.items[*].region}", clusters))
+	assert.Equal(t,
+		fmt.Sprintf("%v;%v;", clusters[0].Name, clusters[1].Name),
+		renderList(t, "go-template={{range .items}}{{.name}};{{end}}", clusters))
+}
@@ -34,7 +34,8 @@ func Test_getTable(t *testing.T) {
 	for _, tt := range tableCases {
 		testname := fmt.Sprintf("Clusters %v", tt.Clusters)
 		t.Run(testname, func(t *testing.T) {
-			r := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, "{{.Name}}-x")
+			names := mustNameTemplate(t, "{{.Name}}-x")
+			r := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, names, defaultTableOptions(t))
 
 			assert.Contains(t, r, fmt.Sprintf("%v", len(tt.Clusters)))
 
@@ -49,7 +50,9 @@ func Test_getTableBrokenTemplate(t *testing.T) {
 	for _, tt := range tableCases {
 		testname := fmt.Sprintf("Clusters %v", tt.Clusters)
 		t.Run(testname, func(t *testing.T) {
-			r := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, "{{.NameX}}")
+			// the template is valid but renders an empty name
+			names := mustNameTemplate(t, `{{tag "missing"}}`)
+			r := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, names, defaultTableOptions(t))
 
 			assert.Contains(t, r, fmt.Sprintf("%v", len(tt.Clusters)))
 
@@ -59,3 +62,24 @@ func Test_getTableBrokenTemplate(t *testing.T) {
 		})
 	}
 }
+
+func Test_getTableWide(t *testing.T) {
+	for _, tt := range tableCases {
+		testname := fmt.Sprintf("Clusters %v", tt.Clusters)
+		t.Run(testname, func(t *testing.T) {
+			wide, err := lookupColumns(wideColumns)
+			assert.NoError(t, err)
+			names := mustNameTemplate(t, "{{.Name}}")
+			r := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, names, tableOptions{Columns: wide})
+			narrow := getTable(convertToInterfaces(tt.Clusters), mockExportable{}, names, defaultTableOptions(t))
+
+			assert.Contains(t, r, "platform")
+			assert.NotContains(t, narrow, "platform")
+			for _, cls := range tt.Clusters {
+				assert.Contains(t, r, cls.VpcID)
+				assert.Contains(t, r, cls.CreatedAt.Format("2006-01-02"))
+				assert.NotContains(t, narrow, cls.VpcID)
+			}
+		})
+	}
+}
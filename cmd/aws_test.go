@@ -83,3 +83,230 @@ func TestQueryAllRegions(t *testing.T) {
 		})
 	}
 }
+
+func TestAllProfilesWithoutProfiles(t *testing.T) {
+	dir, err := os.MkdirTemp("", ".aws")
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{
+		"aws", "list",
+		"--kubeconfig-path", filepath.Join(dir, "kubeconfig"),
+		"--aws-all-profiles",
+	})
+
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected an error when no AWS profiles are defined")
+	}
+}
+
+func TestInvalidRoleARNs(t *testing.T) {
+	dir, err := os.MkdirTemp("", ".kube")
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	rolesFile := filepath.Join(dir, "roles")
+	if err := os.WriteFile(rolesFile, []byte("not-a-role\n"), 0600); err != nil {
+		t.Error(err.Error())
+	}
+
+	tts := [][]string{
+		{"--aws-role-arns", "not-a-role"},
+		{"--aws-role-arns-file", rolesFile},
+		{"--aws-role-arns-file", filepath.Join(dir, "missing")},
+	}
+	for _, tt := range tts {
+		testname := fmt.Sprintf("flags %v", tt)
+		t.Run(testname, func(t *testing.T) {
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(append([]string{
+				"aws", "list",
+				"--kubeconfig-path", filepath.Join(dir, "kubeconfig"),
+			}, tt...))
+
+			if err := cmd.Execute(); err == nil {
+				t.Errorf("Expected an error for %v", tt)
+			}
+		})
+	}
+}
+
+func TestInvalidOrganizationRoleTemplate(t *testing.T) {
+	tts := [][]string{
+		{"--organization-role-template", "arn:aws:iam::{{.AccountId}}:role/ReadOnly"},
+		// the role must be chosen
+		{},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprintf("flags %v", tt), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", ".kube")
+			if err != nil {
+				t.Error(err.Error())
+			}
+			defer os.RemoveAll(dir)
+
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(append([]string{
+				"aws", "list",
+				"--kubeconfig-path", filepath.Join(dir, "kubeconfig"),
+				"--organization",
+			}, tt...))
+
+			if err := cmd.Execute(); err == nil {
+				t.Error("Expected an error for an invalid organization role template")
+			}
+		})
+	}
+}
+
+func TestRegionFilters(t *testing.T) {
+	tts := []struct {
+		Flags []string
+		Check func(region string) bool
+		Valid bool
+	}{
+		{
+			[]string{"--aws-regions", "eu-west-1,us-east-*"},
+			func(r string) bool { return r == "eu-west-1" || strings.HasPrefix(r, "us-east-") },
+			true,
+		},
+		{
+			[]string{"--aws-regions", "eu-*", "--aws-exclude-regions", "eu-west-*,eu-c*"},
+			func(r string) bool {
+				return strings.HasPrefix(r, "eu-") && !strings.HasPrefix(r, "eu-west-") && !strings.HasPrefix(r, "eu-c")
+			},
+			true,
+		},
+		{[]string{"--aws-regions", "eu-["}, nil, false},
+		{[]string{"--aws-regions", "mars-*"}, nil, false},
+	}
+	for _, tt := range tts {
+		testname := fmt.Sprintf("flags %v", tt.Flags)
+		t.Run(testname, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", ".kube")
+			if err != nil {
+				t.Error(err.Error())
+			}
+			defer os.RemoveAll(dir)
+
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(append([]string{
+				"aws", "list",
+				"--kubeconfig-path", filepath.Join(dir, "kubeconfig"),
+			}, tt.Flags...))
+
+			err = cmd.Execute()
+			if !tt.Valid {
+				if err == nil {
+					t.Errorf("Expected an error for %v", tt.Flags)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err.Error())
+			}
+			if len(awsRegions) == 0 {
+				t.Errorf("Expected regions for %v", tt.Flags)
+			}
+			for _, region := range awsRegions {
+				if !tt.Check(region) {
+					t.Errorf("Unexpected region %v in %v", region, awsRegions)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidTimeouts(t *testing.T) {
+	for _, flag := range []string{"--timeout", "--region-timeout"} {
+		testname := fmt.Sprintf("flag %v", flag)
+		t.Run(testname, func(t *testing.T) {
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs([]string{"aws", "list", flag, "soon"})
+
+			if err := cmd.Execute(); err == nil {
+				t.Errorf("Expected an error for %v", flag)
+			}
+		})
+	}
+}
+
+func TestInvalidListColumns(t *testing.T) {
+	tts := [][]string{
+		{"--columns", "name,unknown"},
+		{"--columns", "tags."},
+		{"--sort-by", "unknown"},
+		{"--sort-by", "name:up"},
+		{"--context-name-alias", "{{.Missing}}"},
+		{"--context-name-alias", "{{unknown .Name}}"},
+		{"--account-alias", "123456789012"},
+	}
+	for _, flags := range tts {
+		testname := fmt.Sprintf("flags %v", flags)
+		t.Run(testname, func(t *testing.T) {
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(append([]string{"aws", "list"}, flags...))
+
+			if err := cmd.Execute(); err == nil {
+				t.Errorf("Expected an error for %v", flags)
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	tts := []struct {
+		Filters []string
+		Valid   bool
+	}{
+		{[]string{"tag:env=prod"}, true},
+		{[]string{"status=ACTIVE AND (name~^payments- OR version>=1.28)", "region!=us-east-1"}, true},
+		{[]string{"version>=latest"}, false},
+		{[]string{"status=ACTIVE", "color=blue"}, false},
+	}
+	for _, tt := range tts {
+		testname := fmt.Sprintf("filters %v", tt.Filters)
+		t.Run(testname, func(t *testing.T) {
+			args := []string{"aws", "list", "--aws-regions", "eu-west-1"}
+			for _, f := range tt.Filters {
+				args = append(args, "--filter", f)
+			}
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(args)
+
+			err := cmd.Execute()
+			if !tt.Valid {
+				if err == nil {
+					t.Errorf("Expected an error for %v", tt.Filters)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err.Error())
+			}
+			if clusterFilter == nil {
+				t.Errorf("Expected a filter for %v", tt.Filters)
+			}
+		})
+	}
+}
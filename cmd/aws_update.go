@@ -2,134 +2,171 @@
 package cmd
 
 import (
-	"fmt"
-	"io"
 	"os"
-	"path"
-	"path/filepath"
+	"slices"
 
 	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/mateimicu/kdiscover/internal/cluster"
 	"github.com/mateimicu/kdiscover/internal/kubeconfig"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	backupKubeconfig bool
+	updatePrune  bool
+	updateDryRun bool
 )
 
-func backupKubeConfig(kubeconfigPath string) (string, error) {
-	bName, err := generateBackupName(kubeconfigPath)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"kubeconfig-path": kubeconfigPath,
-			"err":             err.Error(),
-		}).Info("Can't generate backup file name ")
-	}
-	err = copyFs(kubeconfigPath, bName)
-	if err != nil {
-		return "", err
-	}
-	return bName, nil
-}
-
 func newUpdateCommand() *cobra.Command {
 	updateCommand := &cobra.Command{
 		Use:   "update",
 		Short: "Update all EKS Clusters",
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			cmd.Println(cmd.Short)
-
-			remoteEKSClusters := aws.GetEKSClusters(awsRegions)
-			log.Info(remoteEKSClusters)
-
-			cmd.Printf("Found %v clusters remote\n", len(remoteEKSClusters))
-
-			if backupKubeconfig && fileExists(kubeconfigPath) {
-				bName, err := backupKubeConfig(kubeconfigPath)
-				if err != nil {
-					return err
-				}
-				cmd.Printf("Backup kubeconfig to %v\n", bName)
+			splitTemplate, err := getSplitFileTemplate()
+			if err != nil {
+				return err
+			}
+			if err := validateConflictPolicy(); err != nil {
+				return err
 			}
-			kubeconfig, err := kubeconfig.LoadKubeconfig(kubeconfigPath)
+			if err := loadAuth(); err != nil {
+				return err
+			}
+			if err := loadEntryNames(); err != nil {
+				return err
+			}
+
+			discovery, failures, err := discoverEKSClusters(cmd, updatePrune)
 			if err != nil {
 				return err
 			}
+			remoteEKSClusters := selectClusters(discovery)
 
-			for _, cls := range remoteEKSClusters {
-				ctxName, err := cls.PrettyName(alias)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"cluster": cls,
-						"error":   err,
-					}).Info("Can't generate alias for the cluster")
-					continue
+			cmd.Printf("Found %v clusters remote\n", len(remoteEKSClusters))
+			named := nameClusters(remoteEKSClusters, contextNames)
+			if splitTemplate != nil {
+				// the files are written from scratch, only the clusters can conflict
+				named, err = applyConflictPolicy(cmd, named, kubeconfig.New())
+				if err == nil {
+					named, err = nameEntries(named, kubeconfig.New())
 				}
-				kubeconfig.AddCluster(cls, ctxName)
+				if err == nil {
+					err = updateSplitFiles(cmd, named, splitTemplate)
+				}
+			} else {
+				err = updateKubeconfigFile(cmd, discovery, named)
 			}
-			err = kubeconfig.Persist(kubeconfigPath)
 			if err != nil {
-				cmd.Printf("Failed to persist kubeconfig %v", err.Error())
+				return err
 			}
-			return err
+			return reportFailures(cmd, failures)
 		},
 	}
 
-	updateCommand.Flags().BoolVar(&backupKubeconfig, "backup-kubeconfig", true, "Backup cubeconfig before update")
+	addBackupFlags(updateCommand)
+	addSplitFlags(updateCommand)
+	updateCommand.Flags().BoolVar(
+		&updatePrune, "prune", false,
+		"Remove the entries written by kdiscover for clusters that no longer exist in the searched regions")
+	updateCommand.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Prune without asking for confirmation")
+	updateCommand.Flags().BoolVar(
+		&updateDryRun, "dry-run", false,
+		"Print the changes to the kubeconfig as a diff without writing it or creating a backup")
+	addConflictFlags(updateCommand)
+	addEntryNameFlags(updateCommand)
+	addAuthFlags(updateCommand)
 
 	return updateCommand
 }
 
-func copyFs(src, dst string) error {
-	sourceFileStat, err := os.Stat(src)
+// namedCluster is a cluster with its context name
+type namedCluster struct {
+	*cluster.Cluster
+	Context string
+	// ClusterName and UserName are the names of the entries, set by
+	// nameEntries
+	ClusterName string
+	UserName    string
+	SharedUser  bool
+}
+
+// updateKubeconfigFile adds the clusters to the kubeconfig, the backup is
+// taken once the kubeconfig is ready to be written. The kubeconfig is
+// locked from the load to the write, except in a dry-run.
+func updateKubeconfigFile(cmd *cobra.Command, discovery *aws.Discovery, named []namedCluster) error {
+	if !updateDryRun {
+		unlock, err := kubeconfig.Lock(kubeconfigPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	k, err := kubeconfig.LoadKubeconfig(kubeconfigPath)
 	if err != nil {
 		return err
 	}
-
-	if !sourceFileStat.Mode().IsRegular() {
-		return fmt.Errorf("%s is not a regular file", src)
+	k.Version = kdiscoverVersion
+	current := k.DeepCopy()
+	if updatePrune {
+		pruneKubeconfig(cmd, k, discovery, !pruneYes, updateDryRun)
 	}
 
-	source, err := os.Open(filepath.Clean(src))
+	existing, err := existingContexts(k)
 	if err != nil {
 		return err
 	}
-	defer source.Close()
-
-	_, err = os.Stat(dst)
-	if err == nil {
-		return fmt.Errorf("file %s already exists", dst)
+	named, err = applyConflictPolicy(cmd, named, existing)
+	if err != nil {
+		return err
 	}
-
-	destination, err := os.Create(dst)
+	named, err = nameEntries(named, k)
 	if err != nil {
 		return err
 	}
-	defer destination.Close()
-
-	_, err = io.Copy(destination, source)
-	return err
-}
+	for _, cls := range named {
+		k.AddEntry(cls, cls.entryNames())
+	}
+	if updateDryRun {
+		return printKubeconfigDiff(cmd.OutOrStdout(), current, k, kubeconfigPath, kubeconfigPath+" (dry-run)")
+	}
 
-func generateBackupName(origin string) (string, error) {
-	fi, err := os.Stat(origin)
-	if err != nil {
-		return "", err
+	if err := backupKubeconfigFile(cmd); err != nil {
+		return err
 	}
-	if !fi.Mode().IsRegular() {
-		return "", fmt.Errorf("%s is not a regular file", origin)
+	if err := k.PersistLocked(kubeconfigPath); err != nil {
+		cmd.Printf("Failed to persist kubeconfig %v", err.Error())
+		return err
+	}
+	if !slices.Contains(kubeconfigPaths, kubeconfigPath) {
+		cmd.Printf("%v is not in $KUBECONFIG, add it to use the clusters with kubectl\n", kubeconfigPath)
 	}
-	oName := path.Base(origin)
-	oDir := path.Dir(origin)
-	for {
-		if fileExists(path.Join(oDir, oName)) {
-			oName += ".bak"
-		} else {
-			break
+	return nil
+}
+
+// nameClusters renders the context names, the clusters without a name
+// are skipped and the clusters found twice, with different profiles,
+// are kept once. The discovery returns the clusters in the order of the
+// identities so the first profile of --aws-profiles is always kept.
+func nameClusters(clusters []*cluster.Cluster, names *cluster.NameTemplate) []namedCluster {
+	named := make([]namedCluster, 0, len(clusters))
+	seen := map[string]bool{}
+	for _, cls := range clusters {
+		ctxName, err := cls.PrettyName(names)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"cluster": cls,
+				"error":   err,
+			}).Info("Can't generate alias for the cluster")
+			continue
+		}
+		if seen[cls.GetUniqueID()] {
+			continue
 		}
+		seen[cls.GetUniqueID()] = true
+		named = append(named, namedCluster{Cluster: cls, Context: ctxName})
 	}
-	return path.Join(oDir, oName), nil
+	return named
 }
 
 func fileExists(filename string) bool {
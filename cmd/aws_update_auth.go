@@ -0,0 +1,104 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/spf13/cobra"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+var (
+	authType               string
+	authCommand            string
+	authArgs               []string
+	authEnv                []string
+	authAPIVersion         string
+	authInteractiveMode    string
+	authProvideClusterInfo bool
+	// execAuth is the authenticator of the users written by update, nil
+	// for the other commands
+	execAuth *aws.Auth
+)
+
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&authType, "auth", "",
+		fmt.Sprintf("Exec authenticator of the users, one of %v (default aws-cli if the AWS CLI can "+
+			"generate tokens, otherwise iam-authenticator)", strings.Join(aws.AuthTypeNames(), ", ")))
+	cmd.Flags().StringVar(&authCommand, "auth-command", "",
+		"Template for the command of the authenticator, required by --auth=custom")
+	cmd.Flags().StringArrayVar(&authArgs, "auth-args", []string{},
+		"Template for an argument appended to the ones of the authenticator, can be repeated")
+	cmd.Flags().StringArrayVar(&authEnv, "auth-env", []string{},
+		"Environment variable of the authenticator as NAME=TEMPLATE (ex: AWS_REGION={{.Region}}), can be repeated")
+	cmd.Flags().StringVar(&authAPIVersion, "auth-api-version", "v1beta1",
+		"Version of client.authentication.k8s.io used by the authenticator, v1beta1 or v1")
+	cmd.Flags().StringVar(&authInteractiveMode, "auth-interactive-mode", "",
+		"Whether the authenticator reads the standard input: Never, IfAvailable or Always "+
+			"(default IfAvailable for the v1 API version)")
+	cmd.Flags().BoolVar(&authProvideClusterInfo, "auth-provide-cluster-info", false,
+		"Pass the cluster details to the authenticator in KUBERNETES_EXEC_INFO")
+}
+
+func parseAuthTemplate(flag, value string) (*cluster.NameTemplate, error) {
+	tmpl, err := cluster.ParseNameTemplate(value, accountAliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v %q: %w", flag, value, err)
+	}
+	return tmpl, nil
+}
+
+// loadAuth parses the authenticator flags before the discovery starts
+func loadAuth() error {
+	execAuth = nil
+	t, err := aws.ParseAuthType(authType)
+	if err != nil {
+		return err
+	}
+	auth := &aws.Auth{
+		Type:               t,
+		Args:               make([]*cluster.NameTemplate, 0, len(authArgs)),
+		Env:                make([]aws.EnvTemplate, 0, len(authEnv)),
+		InteractiveMode:    clientcmdapi.ExecInteractiveMode(authInteractiveMode),
+		ProvideClusterInfo: authProvideClusterInfo,
+	}
+	if auth.APIVersion, err = aws.ParseAPIVersion(authAPIVersion); err != nil {
+		return err
+	}
+	command := authCommand
+	if command == "" && t.IsKdiscover() {
+		// the users run the token command of this binary, under its name
+		command = kdiscoverCommand
+	}
+	if command != "" {
+		if auth.Command, err = parseAuthTemplate("--auth-command", command); err != nil {
+			return err
+		}
+	}
+	for _, arg := range authArgs {
+		tmpl, err := parseAuthTemplate("--auth-args", arg)
+		if err != nil {
+			return err
+		}
+		auth.Args = append(auth.Args, tmpl)
+	}
+	for _, env := range authEnv {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --auth-env %q, use NAME=TEMPLATE", env)
+		}
+		tmpl, err := parseAuthTemplate("--auth-env", value)
+		if err != nil {
+			return err
+		}
+		auth.Env = append(auth.Env, aws.EnvTemplate{Name: name, Value: tmpl})
+	}
+	if err := auth.Validate(); err != nil {
+		return err
+	}
+	execAuth = auth
+	return nil
+}
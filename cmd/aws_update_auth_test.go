@@ -0,0 +1,102 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type authFlags struct {
+	Type, Command, APIVersion, InteractiveMode string
+	Args, Env                                  []string
+}
+
+func setAuthFlags(t *testing.T, f authFlags) {
+	t.Helper()
+	if f.APIVersion == "" {
+		f.APIVersion = "v1beta1"
+	}
+	authType, authCommand, authAPIVersion, authInteractiveMode = f.Type, f.Command, f.APIVersion, f.InteractiveMode
+	authArgs, authEnv = f.Args, f.Env
+	t.Cleanup(func() {
+		authType, authCommand, authAPIVersion, authInteractiveMode = "", "", "v1beta1", ""
+		authArgs, authEnv = []string{}, []string{}
+		execAuth = nil
+	})
+}
+
+func TestLoadAuth(t *testing.T) {
+	cases := []struct {
+		Flags authFlags
+		Valid bool
+	}{
+		{authFlags{Type: "aws-cli"}, true},
+		{authFlags{Type: "aws-cli", APIVersion: "v1", Env: []string{"AWS_STS_REGIONAL_ENDPOINTS=regional"}}, true},
+		{authFlags{Type: "gcloud"}, false},
+		{authFlags{Type: "aws-cli", APIVersion: "v2"}, false},
+		{authFlags{Type: "aws-cli", InteractiveMode: "Sometimes"}, false},
+		{authFlags{Type: "custom"}, false},
+		{authFlags{Type: "custom", Command: "login", Args: []string{"{{.Name}}"}}, true},
+		{authFlags{Type: "custom", Command: "{{.Missing}}"}, false},
+		{authFlags{Type: "kubelogin"}, false},
+		{authFlags{Type: "kubelogin", Args: []string{"--oidc-issuer-url=https://{{.AccountID}}"}}, true},
+		{authFlags{Type: "aws-cli", Args: []string{"{{unknown}}"}}, false},
+		{authFlags{Type: "aws-cli", Env: []string{"AWS_REGION"}}, false},
+		{authFlags{Type: "aws-cli", Env: []string{"=value"}}, false},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("flags %+v", tt.Flags), func(t *testing.T) {
+			setAuthFlags(t, tt.Flags)
+			err := loadAuth()
+			assert.Equal(t, tt.Valid, err == nil, "%v", err)
+			assert.Equal(t, tt.Valid, execAuth != nil)
+		})
+	}
+}
+
+func TestLoadAuthExecConfig(t *testing.T) {
+	setAuthFlags(t, authFlags{
+		Type:       "aws-cli",
+		APIVersion: "v1",
+		Env:        []string{"AWS_REGION={{.Region}}", "AWS_STS_REGIONAL_ENDPOINTS=regional"},
+	})
+	authProvideClusterInfo = true
+	defer func() { authProvideClusterInfo = false }()
+	assert.NoError(t, loadAuth())
+
+	cls := newEKSCluster("prod", "eu-west-1")
+	assert.Equal(t, "aws-cli", execAuth.Type.String())
+	assert.Equal(t, "client.authentication.k8s.io/v1", execAuth.APIVersion)
+	assert.True(t, execAuth.ProvideClusterInfo)
+	names := make([]string, 0, len(execAuth.Env))
+	for _, env := range execAuth.Env {
+		value, err := env.Value.Execute(cls)
+		assert.NoError(t, err)
+		names = append(names, env.Name+"="+value)
+	}
+	assert.Equal(t, []string{"AWS_REGION=eu-west-1", "AWS_STS_REGIONAL_ENDPOINTS=regional"}, names)
+}
+
+func TestLoadAuthKdiscoverCommand(t *testing.T) {
+	defer func(command string) { kdiscoverCommand = command }(kdiscoverCommand)
+	cls := newEKSCluster("prod", "eu-west-1")
+	tts := []struct {
+		Binary, Flag, Expected string
+	}{
+		{"kdiscover", "", "kdiscover"},
+		{"kubectl-discover", "", "kubectl-discover"},
+		{"kubectl-discover", "/usr/local/bin/kdiscover", "/usr/local/bin/kdiscover"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprintf("%+v", tt), func(t *testing.T) {
+			kdiscoverCommand = tt.Binary
+			setAuthFlags(t, authFlags{Type: "kdiscover", Command: tt.Flag})
+			assert.NoError(t, loadAuth())
+			command, err := execAuth.Command.Execute(cls)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.Expected, command)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+const (
+	conflictError     = "error"
+	conflictSkip      = "skip"
+	conflictSuffix    = "suffix"
+	conflictOverwrite = "overwrite"
+)
+
+var (
+	onConflict       string
+	conflictPolicies = []string{conflictError, conflictSkip, conflictSuffix, conflictOverwrite}
+)
+
+// contextOwner tells which cluster an existing context uses
+type contextOwner interface {
+	ContextOwner(name string) (string, bool)
+}
+
+// contextOwners checks the contexts of several kubeconfigs, in order
+type contextOwners []contextOwner
+
+func (o contextOwners) ContextOwner(name string) (string, bool) {
+	for _, owner := range o {
+		if key, ok := owner.ContextOwner(name); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// existingContexts returns the contexts of the destination together with
+// the ones of the other files of $KUBECONFIG, kubectl merges them so a
+// context with the same name in another file would shadow the written
+// one or be shadowed by it
+func existingContexts(destination *kubeconfig.Kubeconfig) (contextOwner, error) {
+	others := slices.DeleteFunc(slices.Clone(kubeconfigPaths), func(p string) bool {
+		return filepath.Clean(p) == filepath.Clean(kubeconfigPath)
+	})
+	if len(others) == 0 {
+		return destination, nil
+	}
+	merged, err := kubeconfig.LoadMerged(others)
+	if err != nil {
+		return nil, err
+	}
+	return contextOwners{destination, merged}, nil
+}
+
+// contextConflict is a context name wanted by a cluster and already used
+// by another cluster of the update or by an existing context
+type contextConflict struct {
+	Context string
+	Cluster string
+	With    string
+	Action  string
+}
+
+func addConflictFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&onConflict, "on-conflict", conflictError,
+		fmt.Sprintf("What to do when a context name is used by another cluster: %v. "+
+			"suffix appends -2, -3 and so on to the name", strings.Join(conflictPolicies, ", ")))
+}
+
+func validateConflictPolicy() error {
+	for _, p := range conflictPolicies {
+		if onConflict == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --on-conflict %q, use one of %v", onConflict, strings.Join(conflictPolicies, ", "))
+}
+
+// contextUser returns who else uses the context name, the contexts of
+// the same cluster are not conflicts so the updates can replace them
+func contextUser(name string, cls namedCluster, taken map[string]namedCluster, existing contextOwner) (string, bool) {
+	if other, ok := taken[name]; ok {
+		return "cluster " + other.ID, other.GetUniqueID() != cls.GetUniqueID()
+	}
+	if key, ok := existing.ContextOwner(name); ok && key != cls.GetUniqueID() {
+		return "existing context of " + key, true
+	}
+	return "", false
+}
+
+func suffixContext(cls namedCluster, taken map[string]namedCluster, existing contextOwner) string {
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("%v-%v", cls.Context, i)
+		if _, conflict := contextUser(name, cls, taken, existing); !conflict {
+			return name
+		}
+	}
+}
+
+// resolveContextConflicts applies the policy to the clusters with the
+// same context name as another cluster or an existing context. The
+// clusters are sorted first so the suffixes are stable between runs.
+func resolveContextConflicts(
+	named []namedCluster, existing contextOwner, policy string,
+) ([]namedCluster, []contextConflict) {
+	sorted := append([]namedCluster{}, named...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GetUniqueID() < sorted[j].GetUniqueID()
+	})
+
+	resolved := make([]namedCluster, 0, len(sorted))
+	conflicts := []contextConflict{}
+	taken := map[string]namedCluster{}
+	for _, cls := range sorted {
+		with, conflict := contextUser(cls.Context, cls, taken, existing)
+		if !conflict {
+			taken[cls.Context] = cls
+			resolved = append(resolved, cls)
+			continue
+		}
+
+		c := contextConflict{Context: cls.Context, Cluster: cls.ID, With: with}
+		switch policy {
+		case conflictError:
+			c.Action = "error"
+		case conflictSkip:
+			c.Action = "skipped"
+		case conflictOverwrite:
+			c.Action = "overwritten"
+			if other, ok := taken[cls.Context]; ok {
+				// the other cluster would be written without a context
+				resolved = slices.DeleteFunc(resolved, func(r namedCluster) bool {
+					return r.GetUniqueID() == other.GetUniqueID()
+				})
+				c.Action = fmt.Sprintf("overwritten, cluster %v is not written", other.ID)
+			}
+			taken[cls.Context] = cls
+			resolved = append(resolved, cls)
+		case conflictSuffix:
+			cls.Context = suffixContext(cls, taken, existing)
+			c.Action = "renamed to " + cls.Context
+			taken[cls.Context] = cls
+			resolved = append(resolved, cls)
+		}
+		conflicts = append(conflicts, c)
+	}
+	return resolved, conflicts
+}
+
+func getConflictsTable(conflicts []contextConflict) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Context", "Cluster", "Used By", "Action"})
+	for _, c := range conflicts {
+		tw.AppendRow(table.Row{c.Context, c.Cluster, c.With, c.Action})
+	}
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+// applyConflictPolicy reports the conflicts and fails if the policy is
+// error, nothing is written in that case
+func applyConflictPolicy(cmd *cobra.Command, named []namedCluster, existing contextOwner) ([]namedCluster, error) {
+	resolved, conflicts := resolveContextConflicts(named, existing, onConflict)
+	if len(conflicts) == 0 {
+		return resolved, nil
+	}
+	cmd.Printf("Found %v context name conflicts\n", len(conflicts))
+	cmd.Println(getConflictsTable(conflicts))
+	if onConflict == conflictError {
+		return nil, fmt.Errorf("%v context name conflicts, use a more specific --context-name-alias "+
+			"or --on-conflict with %v, %v or %v",
+			len(conflicts), conflictSkip, conflictSuffix, conflictOverwrite)
+	}
+	return resolved, nil
+}
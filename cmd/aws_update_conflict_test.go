@@ -0,0 +1,149 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockContexts are the existing contexts, by name, with their cluster
+type mockContexts map[string]string
+
+func (m mockContexts) ContextOwner(name string) (string, bool) {
+	key, ok := m[name]
+	return key, ok
+}
+
+func newConflictingClusters(t *testing.T) []namedCluster {
+	t.Helper()
+	return newNamedClusters(t, "{{.Name}}",
+		newEKSCluster("prod", "us-east-1"),
+		newEKSCluster("dev", "us-east-1"),
+		newEKSCluster("prod", "eu-west-1"),
+	)
+}
+
+func TestResolveContextConflicts(t *testing.T) {
+	existing := mockContexts{"dev": "manual"}
+	cases := []struct {
+		Policy   string
+		Contexts []string
+		Actions  []string
+	}{
+		{conflictError, []string{"prod"}, []string{"error", "error"}},
+		{conflictSkip, []string{"prod"}, []string{"skipped", "skipped"}},
+		{conflictSuffix, []string{"prod", "dev-2", "prod-2"}, []string{"renamed to dev-2", "renamed to prod-2"}},
+		// the cluster losing its context is not written at all
+		{conflictOverwrite, []string{"dev", "prod"}, []string{
+			"overwritten", "overwritten, cluster arn:aws:eks:eu-west-1:123456789012:cluster/prod is not written",
+		}},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("policy %v", tt.Policy), func(t *testing.T) {
+			resolved, conflicts := resolveContextConflicts(newConflictingClusters(t), existing, tt.Policy)
+			contexts := []string{}
+			for _, cls := range resolved {
+				contexts = append(contexts, cls.Context)
+			}
+			assert.Equal(t, tt.Contexts, contexts)
+
+			actions := []string{}
+			for _, c := range conflicts {
+				actions = append(actions, c.Action)
+			}
+			assert.Equal(t, tt.Actions, actions)
+			if assert.Len(t, conflicts, 2) {
+				assert.Equal(t, "existing context of manual", conflicts[0].With)
+				assert.Equal(t, "cluster arn:aws:eks:eu-west-1:123456789012:cluster/prod", conflicts[1].With)
+			}
+		})
+	}
+}
+
+func TestResolveContextConflictsStable(t *testing.T) {
+	named := newConflictingClusters(t)
+	// the contexts written by a previous run with --on-conflict suffix
+	existing := mockContexts{
+		"prod":   named[2].GetUniqueID(),
+		"prod-2": named[0].GetUniqueID(),
+		"dev":    named[1].GetUniqueID(),
+	}
+	resolved, conflicts := resolveContextConflicts(named, existing, conflictSuffix)
+	assert.Len(t, conflicts, 1)
+	contexts := map[string]string{}
+	for _, cls := range resolved {
+		contexts[cls.Context] = cls.GetUniqueID()
+	}
+	assert.Equal(t, map[string]string(existing), contexts)
+}
+
+func TestApplyConflictPolicy(t *testing.T) {
+	defer func() { onConflict = conflictError }()
+	k := kubeconfig.New()
+	k.AddCluster(newEKSCluster("other", "us-east-1"), "dev")
+
+	onConflict = conflictError
+	cmd, out := newTestCommand("")
+	_, err := applyConflictPolicy(cmd, newConflictingClusters(t), k)
+	assert.ErrorContains(t, err, "2 context name conflicts")
+	assert.Contains(t, out.String(), "USED BY")
+
+	onConflict = conflictSkip
+	cmd, out = newTestCommand("")
+	resolved, err := applyConflictPolicy(cmd, newConflictingClusters(t), k)
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 1)
+	assert.Contains(t, out.String(), "skipped")
+
+	// the same cluster keeps its context
+	onConflict = conflictError
+	k = kubeconfig.New()
+	k.AddCluster(newEKSCluster("prod", "us-east-1"), "prod")
+	resolved, err = applyConflictPolicy(cmd, newConflictingClusters(t)[:1], k)
+	assert.NoError(t, err)
+	assert.Len(t, resolved, 1)
+}
+
+func TestExistingContexts(t *testing.T) {
+	defer func(path string, paths []string) {
+		kubeconfigPath, kubeconfigPaths = path, paths
+	}(kubeconfigPath, kubeconfigPaths)
+	dir := t.TempDir()
+	kubeconfigPath = filepath.Join(dir, "config")
+	other := filepath.Join(dir, "other")
+	kubeconfigPaths = []string{kubeconfigPath, other}
+
+	k := kubeconfig.New()
+	k.AddCluster(newEKSCluster("other", "us-east-1"), "prod")
+	assert.NoError(t, k.Persist(other))
+	destination := kubeconfig.New()
+	destination.AddCluster(newEKSCluster("manual", "us-east-1"), "dev")
+
+	existing, err := existingContexts(destination)
+	assert.NoError(t, err)
+	cmd, _ := newTestCommand("")
+	_, err = applyConflictPolicy(cmd, newConflictingClusters(t), existing)
+	// dev in the destination, both prod with the context of the other file
+	assert.ErrorContains(t, err, "3 context name conflicts")
+
+	// the destination is not read from the disk, it may have been pruned
+	kubeconfigPaths = []string{kubeconfigPath}
+	existing, err = existingContexts(destination)
+	assert.NoError(t, err)
+	assert.Equal(t, destination, existing)
+}
+
+func TestInvalidConflictPolicy(t *testing.T) {
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{
+		"aws", "update", "--on-conflict", "rename", "--kubeconfig-path", filepath.Join(t.TempDir(), "config"),
+	})
+	assert.ErrorContains(t, cmd.Execute(), "unknown --on-conflict")
+}
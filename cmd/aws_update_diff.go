@@ -0,0 +1,81 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"golang.org/x/term"
+)
+
+var diffActionColors = map[string]text.Color{
+	kubeconfig.ActionAdded:   text.FgGreen,
+	kubeconfig.ActionChanged: text.FgYellow,
+	kubeconfig.ActionRemoved: text.FgRed,
+}
+
+// isTerminal tells if colors can be used on the writer
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func paint(color text.Color, s string, enabled bool) string {
+	if !enabled {
+		return s
+	}
+	return color.Sprint(s)
+}
+
+// colorizeDiff colors the added lines in green, the removed ones in red
+// and the hunk headers in cyan
+func colorizeDiff(diff string, enabled bool) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = paint(text.Bold, line, enabled)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = paint(text.FgGreen, line, enabled)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = paint(text.FgRed, line, enabled)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = paint(text.FgCyan, line, enabled)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+func getChangesTable(changes []kubeconfig.Change, colors bool) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Kind", "Name", "Change"})
+	for _, c := range changes {
+		tw.AppendRow(table.Row{c.Kind, c.Name, paint(diffActionColors[c.Action], c.Action, colors)})
+	}
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+// printKubeconfigDiff shows the changes between two kubeconfigs
+func printKubeconfigDiff(w io.Writer, before, after *kubeconfig.Kubeconfig, beforeName, afterName string) error {
+	changes, diff, err := kubeconfig.Diff(before, after, beforeName, afterName)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		_, err = io.WriteString(w, "No changes to "+beforeName+"\n")
+		return err
+	}
+
+	colors := isTerminal(w)
+	if _, err = io.WriteString(w, getChangesTable(changes, colors)+"\n"); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, colorizeDiff(diff, colors))
+	return err
+}
@@ -0,0 +1,78 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorizeDiff(t *testing.T) {
+	t.Parallel()
+	diff := "--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n same\n"
+	assert.Equal(t, diff, colorizeDiff(diff, false))
+
+	colored := colorizeDiff(diff, true)
+	assert.NotEqual(t, diff, colored)
+	assert.Contains(t, colored, "\x1b[32m+new\n")
+	assert.Contains(t, colored, "\x1b[31m-old\n")
+	assert.Contains(t, colored, " same\n")
+}
+
+func TestPrintKubeconfigDiff(t *testing.T) {
+	t.Parallel()
+	before := kubeconfig.New()
+	after := before.DeepCopy()
+	cls := cluster.GetMockClusters(1)[0]
+	after.AddCluster(cls, "new-context")
+
+	var out bytes.Buffer
+	assert.NoError(t, printKubeconfigDiff(&out, before, after, "config", "config (dry-run)"))
+	assert.Contains(t, out.String(), "new-context")
+	assert.Contains(t, out.String(), "added")
+	assert.Contains(t, out.String(), "+++ config (dry-run)")
+	assert.NotContains(t, out.String(), "\x1b[")
+
+	out.Reset()
+	assert.NoError(t, printKubeconfigDiff(&out, before, before.DeepCopy(), "config", "config (dry-run)"))
+	assert.Equal(t, "No changes to config\n", out.String())
+}
+
+func TestUpdateDryRun(t *testing.T) {
+	dir, err := os.MkdirTemp("", ".kube")
+	if err != nil {
+		t.Error(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kubeconfig")
+	k := kubeconfig.New()
+	k.AddCluster(cluster.GetMockClusters(1)[0], "existing")
+	if err := k.Persist(path); err != nil {
+		t.Error(err.Error())
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Error(err.Error())
+	}
+
+	var out bytes.Buffer
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"aws", "update", "--dry-run", "--aws-regions", "eu-west-1", "--kubeconfig-path", path})
+	if err := cmd.Execute(); err != nil {
+		t.Error(err.Error())
+	}
+
+	assert.Contains(t, out.String(), "No changes to "+path)
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, original, current)
+	assert.NoDirExists(t, filepath.Join(dir, "kdiscover-backups"))
+}
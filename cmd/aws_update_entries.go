@@ -0,0 +1,154 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clusterNameTemplate string
+	userNameTemplate    string
+	shareUsers          bool
+	clusterEntryNames   *cluster.NameTemplate
+	userEntryNames      *cluster.NameTemplate
+)
+
+// entryOwner tells which cluster an existing cluster or user belongs to
+type entryOwner interface {
+	ClusterOwner(name string) (string, bool)
+	UserOwner(name string) (string, bool)
+}
+
+func addEntryNameFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clusterNameTemplate, "cluster-name-template", "",
+		"Template for the names of the cluster entries, with the same fields and functions as "+
+			"--context-name-alias (default the unique ID of the cluster)")
+	cmd.Flags().StringVar(&userNameTemplate, "user-name-template", "",
+		"Template for the names of the user entries (default the name of the cluster entry)")
+	cmd.Flags().BoolVar(&shareUsers, "share-users", false,
+		"Write one user for the clusters with the same --user-name-template and the same credentials")
+}
+
+// loadEntryNames parses the templates before the discovery starts, it
+// needs the authenticator of loadAuth
+func loadEntryNames() error {
+	var err error
+	clusterEntryNames, userEntryNames = nil, nil
+	if clusterNameTemplate != "" {
+		if clusterEntryNames, err = cluster.ParseNameTemplate(clusterNameTemplate, accountAliases); err != nil {
+			return fmt.Errorf("invalid --cluster-name-template: %w", err)
+		}
+	}
+	if userNameTemplate != "" {
+		if userEntryNames, err = cluster.ParseNameTemplate(userNameTemplate, accountAliases); err != nil {
+			return fmt.Errorf("invalid --user-name-template: %w", err)
+		}
+	}
+	if shareUsers && userEntryNames == nil {
+		return fmt.Errorf("--share-users needs --user-name-template, for example '{{.Profile}}-{{.RoleARN}}'")
+	}
+	if shareUsers && execAuth != nil && execAuth.ClusterSpecific() {
+		return fmt.Errorf("--share-users can't be used with --auth=%v, its arguments include the cluster, "+
+			"use --auth=kubelogin or --auth=custom", execAuth.Type)
+	}
+	return nil
+}
+
+func (cls namedCluster) entryNames() kubeconfig.EntryNames {
+	return kubeconfig.EntryNames{
+		Cluster: cls.ClusterName, User: cls.UserName, Context: cls.Context, SharedUser: cls.SharedUser,
+	}
+}
+
+func renderEntryNames(cls *namedCluster) error {
+	var err error
+	cls.ClusterName = cls.GetUniqueID()
+	if clusterEntryNames != nil {
+		if cls.ClusterName, err = cls.PrettyName(clusterEntryNames); err != nil {
+			return err
+		}
+	}
+	cls.UserName = cls.ClusterName
+	if userEntryNames != nil {
+		if cls.UserName, err = cls.PrettyName(userEntryNames); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameCredentials(a, b namedCluster) bool {
+	return reflect.DeepEqual(a.GetConfigAuthInfo(), b.GetConfigAuthInfo())
+}
+
+// checkUser allows a user name used by several clusters only if they are
+// shared and have the same credentials, the shared users written before
+// have no ID and can be replaced
+func checkUser(cls namedCluster, users map[string]namedCluster, existing entryOwner) error {
+	if first, ok := users[cls.UserName]; ok {
+		if !shareUsers {
+			return fmt.Errorf("clusters %v and %v have the same user name %q, use --share-users to write it once",
+				first.ID, cls.ID, cls.UserName)
+		}
+		if !sameCredentials(first, cls) {
+			return fmt.Errorf("clusters %v and %v can't share the user %q, they need different credentials",
+				first.ID, cls.ID, cls.UserName)
+		}
+		return nil
+	}
+	if owner, ok := existing.UserOwner(cls.UserName); ok && owner != "" && owner != cls.GetUniqueID() {
+		return fmt.Errorf("the user name %q of cluster %v is used by %v in the kubeconfig", cls.UserName, cls.ID, owner)
+	}
+	return nil
+}
+
+// nameEntries renders the names of the cluster and user entries, they
+// must not replace the entries of other clusters
+func nameEntries(named []namedCluster, existing entryOwner) ([]namedCluster, error) {
+	clusters := map[string]namedCluster{}
+	users := map[string]namedCluster{}
+	shared := map[string]bool{}
+	errs := []error{}
+	for i := range named {
+		cls := &named[i]
+		if err := renderEntryNames(cls); err != nil {
+			errs = append(errs, fmt.Errorf("can't name the entries of cluster %v: %w", cls.ID, err))
+			continue
+		}
+		if other, ok := clusters[cls.ClusterName]; ok {
+			errs = append(errs, fmt.Errorf("clusters %v and %v have the same cluster name %q",
+				other.ID, cls.ID, cls.ClusterName))
+			continue
+		}
+		if owner, ok := existing.ClusterOwner(cls.ClusterName); ok && owner != cls.GetUniqueID() {
+			errs = append(errs, fmt.Errorf("the cluster name %q of cluster %v is used by %v in the kubeconfig",
+				cls.ClusterName, cls.ID, owner))
+			continue
+		}
+		clusters[cls.ClusterName] = *cls
+
+		if err := checkUser(*cls, users, existing); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := users[cls.UserName]; ok {
+			shared[cls.UserName] = true
+		} else {
+			users[cls.UserName] = *cls
+		}
+	}
+	if len(errs) != 0 {
+		errs = append(errs, fmt.Errorf("use more specific --cluster-name-template or --user-name-template"))
+		return nil, errors.Join(errs...)
+	}
+	for i := range named {
+		named[i].SharedUser = shared[named[i].UserName]
+	}
+	return named, nil
+}
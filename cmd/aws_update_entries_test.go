@@ -0,0 +1,159 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func setEntryNameFlags(t *testing.T, clusterName, userName string, share bool) {
+	t.Helper()
+	clusterNameTemplate, userNameTemplate, shareUsers = clusterName, userName, share
+	// the clusters of the tests are authenticated with a profile only
+	execAuth = nil
+	t.Cleanup(func() {
+		clusterNameTemplate, userNameTemplate, shareUsers = "", "", false
+		clusterEntryNames, userEntryNames = nil, nil
+	})
+	assert.NoError(t, loadEntryNames())
+}
+
+// newProfileClusters returns clusters authenticated with a command that
+// only depends on the profile
+func newProfileClusters(t *testing.T) []namedCluster {
+	t.Helper()
+	clusters := []*cluster.Cluster{
+		newEKSCluster("prod", "eu-west-1"),
+		newEKSCluster("dev", "eu-west-1"),
+	}
+	for _, cls := range clusters {
+		cls.Profile = "team"
+		cls.GenerateAuthInfo = func(cls *cluster.Cluster) *clientcmdapi.AuthInfo {
+			authInfo := clientcmdapi.NewAuthInfo()
+			authInfo.Exec = &clientcmdapi.ExecConfig{Command: "login", Args: []string{"--profile", cls.Profile}}
+			return authInfo
+		}
+	}
+	return newNamedClusters(t, "{{.Name}}", clusters...)
+}
+
+func TestLoadEntryNames(t *testing.T) {
+	cases := []struct {
+		Cluster, User string
+		Share         bool
+		Valid         bool
+	}{
+		{"", "", false, true},
+		{"{{.Region}}-{{.Name}}", "{{.Profile}}", true, true},
+		{"{{.Missing}}", "", false, false},
+		{"", "{{unknown}}", false, false},
+		{"", "", true, false},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("templates %+v", tt), func(t *testing.T) {
+			clusterNameTemplate, userNameTemplate, shareUsers, execAuth = tt.Cluster, tt.User, tt.Share, nil
+			defer func() { clusterNameTemplate, userNameTemplate, shareUsers = "", "", false }()
+			assert.Equal(t, tt.Valid, loadEntryNames() == nil)
+		})
+	}
+}
+
+func TestLoadEntryNamesShareUsers(t *testing.T) {
+	defer func() {
+		userNameTemplate, shareUsers, execAuth = "", false, nil
+		userEntryNames = nil
+	}()
+	userNameTemplate, shareUsers = "{{.Profile}}", true
+	for _, tt := range []struct {
+		Auth  authFlags
+		Valid bool
+	}{
+		{authFlags{Type: "aws-cli"}, false},
+		{authFlags{Type: "iam-authenticator"}, false},
+		{authFlags{Type: "kdiscover"}, false},
+		{authFlags{Type: "custom", Command: "login", Args: []string{"--profile", "{{.Profile}}"}}, true},
+	} {
+		t.Run(tt.Auth.Type, func(t *testing.T) {
+			setAuthFlags(t, tt.Auth)
+			assert.NoError(t, loadAuth())
+			assert.Equal(t, tt.Valid, loadEntryNames() == nil)
+		})
+	}
+}
+
+func TestNameEntries(t *testing.T) {
+	cases := []struct {
+		Cluster, User string
+		Share         bool
+		Clusters      []string
+		Users         []string
+		Shared        bool
+		Err           string
+	}{
+		{"", "", false, []string{"", ""}, []string{"", ""}, false, ""},
+		{"{{.Name}}", "", false, []string{"prod", "dev"}, []string{"prod", "dev"}, false, ""},
+		{"{{.Name}}", "{{.Profile}}", true, []string{"prod", "dev"}, []string{"team", "team"}, true, ""},
+		{"{{.Region}}", "", false, nil, nil, false, `same cluster name "eu-west-1"`},
+		{"{{.Name}}", "{{.Profile}}", false, nil, nil, false, "use --share-users"},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("templates %+v", tt), func(t *testing.T) {
+			setEntryNameFlags(t, tt.Cluster, tt.User, tt.Share)
+			named, err := nameEntries(newProfileClusters(t), kubeconfig.New())
+			if tt.Err != "" {
+				assert.ErrorContains(t, err, tt.Err)
+				return
+			}
+			assert.NoError(t, err)
+			for i, cls := range named {
+				// the default names are the unique IDs
+				assert.Equal(t, tt.Clusters[i], defaultName(tt.Clusters[i], cls.ClusterName, cls))
+				assert.Equal(t, tt.Users[i], defaultName(tt.Users[i], cls.UserName, cls))
+				assert.Equal(t, tt.Shared, cls.SharedUser)
+			}
+		})
+	}
+}
+
+func defaultName(expected, name string, cls namedCluster) string {
+	if expected == "" && name == cls.GetUniqueID() {
+		return ""
+	}
+	return name
+}
+
+func TestNameEntriesCredentials(t *testing.T) {
+	setEntryNameFlags(t, "{{.Name}}", "{{.Profile}}", true)
+	named := newProfileClusters(t)
+	named[1].GenerateAuthInfo = func(_ *cluster.Cluster) *clientcmdapi.AuthInfo {
+		return clientcmdapi.NewAuthInfo()
+	}
+	_, err := nameEntries(named, kubeconfig.New())
+	assert.ErrorContains(t, err, "different credentials")
+}
+
+func TestNameEntriesExisting(t *testing.T) {
+	setEntryNameFlags(t, "{{.Name}}", "{{.Profile}}", true)
+	k := kubeconfig.New()
+	// written by a previous update
+	for _, cls := range newProfileClusters(t) {
+		k.AddEntry(cls, kubeconfig.EntryNames{Cluster: cls.Name, User: "team", Context: cls.Context, SharedUser: true})
+	}
+	_, err := nameEntries(newProfileClusters(t), k)
+	assert.NoError(t, err)
+
+	other := newEKSCluster("other", "eu-west-1")
+	k.AddEntry(other, kubeconfig.EntryNames{Cluster: "dev", User: "other", Context: "other"})
+	_, err = nameEntries(newProfileClusters(t), k)
+	assert.ErrorContains(t, err, `cluster name "dev"`)
+
+	k = kubeconfig.New()
+	k.AddEntry(other, kubeconfig.EntryNames{User: "team", Context: "other"})
+	_, err = nameEntries(newProfileClusters(t), k)
+	assert.ErrorContains(t, err, `user name "team"`)
+}
@@ -0,0 +1,234 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	splitByCluster = "cluster"
+	splitByAccount = "account"
+	splitByRegion  = "region"
+
+	splitIndexKind = "KubeconfigIndex"
+)
+
+var (
+	splitBy        string
+	splitOutputDir string
+	splitFileName  string
+	splitIndexName string
+
+	// splitGroups returns the group of the cluster and the default
+	// template of the file name
+	splitGroups = map[string]struct {
+		Group    func(cls *cluster.Cluster) splitGroup
+		FileName string
+	}{
+		splitByCluster: {
+			Group: func(cls *cluster.Cluster) splitGroup {
+				return splitGroup{Name: cls.Name, AccountID: cls.AccountID, AccountName: cls.AccountName, Region: cls.Region}
+			},
+			FileName: "{{.AccountID}}-{{.Region}}-{{.Name}}.yaml",
+		},
+		splitByAccount: {
+			Group: func(cls *cluster.Cluster) splitGroup {
+				return splitGroup{AccountID: cls.AccountID, AccountName: cls.AccountName}
+			},
+			FileName: "{{.AccountID}}.yaml",
+		},
+		splitByRegion: {
+			Group: func(cls *cluster.Cluster) splitGroup {
+				return splitGroup{Region: cls.Region}
+			},
+			FileName: "{{.Region}}.yaml",
+		},
+	}
+)
+
+// splitGroup is the data of the file name template, only the fields of
+// the --split-by value are set
+type splitGroup struct {
+	Name        string
+	AccountID   string
+	AccountName string
+	Region      string
+}
+
+type splitFile struct {
+	splitGroup
+	// Path is relative to --output-dir
+	Path       string
+	Contexts   []string
+	kubeconfig *kubeconfig.Kubeconfig
+}
+
+// splitIndex lists the written files, it has the same versioning as the
+// outputs of aws list
+type splitIndex struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Files      []splitIndexFile `json:"files"`
+}
+
+type splitIndexFile struct {
+	Path        string   `json:"path"`
+	Name        string   `json:"name,omitempty"`
+	AccountID   string   `json:"accountID,omitempty"`
+	AccountName string   `json:"accountName,omitempty"`
+	Region      string   `json:"region,omitempty"`
+	Contexts    []string `json:"contexts"`
+}
+
+func addSplitFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&splitBy, "split-by", "",
+		fmt.Sprintf("Write the clusters to one file per %v, %v or %v in --output-dir instead of the kubeconfig",
+			splitByCluster, splitByAccount, splitByRegion))
+	cmd.Flags().StringVar(&splitOutputDir, "output-dir", "", "Directory of the files written with --split-by")
+	cmd.Flags().StringVar(&splitFileName, "split-file-name", "",
+		fmt.Sprintf("Template for the file names relative to --output-dir, with the fields "+
+			"Name, AccountID, AccountName and Region (default %q, %q or %q)",
+			splitGroups[splitByCluster].FileName, splitGroups[splitByAccount].FileName,
+			splitGroups[splitByRegion].FileName))
+	cmd.Flags().StringVar(&splitIndexName, "split-index", "",
+		"Name of an index file listing the files written with --split-by, relative to --output-dir")
+}
+
+// getSplitFileTemplate validates the split flags before the discovery
+// starts, nil is returned if the clusters are not split
+func getSplitFileTemplate() (*template.Template, error) {
+	if splitBy == "" {
+		if splitOutputDir != "" || splitFileName != "" || splitIndexName != "" {
+			return nil, fmt.Errorf("--output-dir, --split-file-name and --split-index need --split-by")
+		}
+		return nil, nil
+	}
+	g, ok := splitGroups[splitBy]
+	if !ok {
+		return nil, fmt.Errorf("can't split by %q, use %v, %v or %v", splitBy, splitByCluster, splitByAccount, splitByRegion)
+	}
+	if splitOutputDir == "" {
+		return nil, fmt.Errorf("--split-by needs --output-dir")
+	}
+	if updatePrune {
+		return nil, fmt.Errorf("--prune can't be used with --split-by")
+	}
+	if splitIndexName != "" && !filepath.IsLocal(splitIndexName) {
+		return nil, fmt.Errorf("the index file %q is not in --output-dir", splitIndexName)
+	}
+	name := splitFileName
+	if name == "" {
+		name = g.FileName
+	}
+	return template.New("split-file-name").Option("missingkey=error").Parse(name)
+}
+
+func getSplitPath(tmpl *template.Template, group splitGroup) (string, error) {
+	var path bytes.Buffer
+	if err := tmpl.Execute(&path, group); err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(path.String()) {
+		return "", fmt.Errorf("the file %q of %+v is not in --output-dir", path.String(), group)
+	}
+	return filepath.Clean(path.String()), nil
+}
+
+// splitClusters builds a kubeconfig for every group of clusters, the
+// files with one cluster use it as the current context
+func splitClusters(clusters []namedCluster, by string, tmpl *template.Template, version string) ([]*splitFile, error) {
+	files := map[splitGroup]*splitFile{}
+	groups := map[string]splitGroup{}
+	for _, cls := range clusters {
+		group := splitGroups[by].Group(cls.Cluster)
+		f, ok := files[group]
+		if !ok {
+			path, err := getSplitPath(tmpl, group)
+			if err != nil {
+				return nil, err
+			}
+			if other, ok := groups[path]; ok {
+				return nil, fmt.Errorf("%+v and %+v would be written to the same file %v", other, group, path)
+			}
+			groups[path] = group
+			f = &splitFile{splitGroup: group, Path: path, kubeconfig: kubeconfig.New()}
+			f.kubeconfig.Version = version
+			files[group] = f
+		}
+		f.kubeconfig.AddEntry(cls, cls.entryNames())
+		f.Contexts = append(f.Contexts, cls.Context)
+	}
+
+	sorted := make([]*splitFile, 0, len(files))
+	for _, f := range files {
+		sort.Strings(f.Contexts)
+		if len(f.Contexts) == 1 {
+			f.kubeconfig.SetCurrentContext(f.Contexts[0])
+		}
+		sorted = append(sorted, f)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted, nil
+}
+
+func writeSplitIndex(files []*splitFile) error {
+	index := splitIndex{APIVersion: listAPIVersion, Kind: splitIndexKind, Files: []splitIndexFile{}}
+	for _, f := range files {
+		index.Files = append(index.Files, splitIndexFile{
+			Path:        filepath.ToSlash(f.Path),
+			Name:        f.Name,
+			AccountID:   f.AccountID,
+			AccountName: f.AccountName,
+			Region:      f.Region,
+			Contexts:    f.Contexts,
+		})
+	}
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return kubeconfig.WriteFile(filepath.Join(splitOutputDir, splitIndexName), data)
+}
+
+// updateSplitFiles replaces the files of the groups, the files of the
+// groups without clusters are left untouched
+func updateSplitFiles(cmd *cobra.Command, clusters []namedCluster, tmpl *template.Template) error {
+	files, err := splitClusters(clusters, splitBy, tmpl, kdiscoverVersion)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		path := filepath.Join(splitOutputDir, f.Path)
+		if updateDryRun {
+			current, err := kubeconfig.LoadKubeconfig(path)
+			if err != nil {
+				return err
+			}
+			err = printKubeconfigDiff(cmd.OutOrStdout(), current, f.kubeconfig, path, path+" (dry-run)")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f.kubeconfig.Persist(path); err != nil {
+			return err
+		}
+		cmd.Printf("Wrote %v to %v\n", strings.Join(f.Contexts, ", "), path)
+	}
+	if splitIndexName == "" || updateDryRun {
+		return nil
+	}
+	return writeSplitIndex(files)
+}
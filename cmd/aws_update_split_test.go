@@ -0,0 +1,141 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func newSplitClusters(t *testing.T) []namedCluster {
+	t.Helper()
+	clusters := []*cluster.Cluster{
+		newEKSCluster("prod", "eu-west-1"),
+		newEKSCluster("dev", "eu-west-1"),
+		newEKSCluster("prod", "us-east-1"),
+	}
+	clusters[0].AccountID = "111111111111"
+	clusters[1].AccountID = "222222222222"
+	clusters[2].AccountID = "111111111111"
+	return newNamedClusters(t, "{{.Region}}-{{.Name}}", clusters...)
+}
+
+func setSplitFlags(t *testing.T, by, dir, fileName, index string) {
+	t.Helper()
+	splitBy, splitOutputDir, splitFileName, splitIndexName = by, dir, fileName, index
+	// left by the commands run in the other tests
+	updatePrune, updateDryRun = false, false
+	t.Cleanup(func() {
+		splitBy, splitOutputDir, splitFileName, splitIndexName = "", "", "", ""
+	})
+}
+
+func TestGetSplitFileTemplate(t *testing.T) {
+	cases := []struct {
+		By, Dir, FileName, Index string
+		Nil, Err                 bool
+	}{
+		{"", "", "", "", true, false},
+		{"", "out", "", "", false, true},
+		{"", "", "", "index.yaml", false, true},
+		{"cluster", "", "", "", false, true},
+		{"tag", "out", "", "", false, true},
+		{"account", "out", "", "", false, false},
+		{"account", "out", "{{.Missing", "", false, true},
+		{"region", "out", "", "../index.yaml", false, true},
+		{"region", "out", "{{.Region}}/config", "index.yaml", false, false},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("split %+v", tt), func(t *testing.T) {
+			setSplitFlags(t, tt.By, tt.Dir, tt.FileName, tt.Index)
+			tmpl, err := getSplitFileTemplate()
+			if tt.Err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.Nil, tmpl == nil)
+		})
+	}
+}
+
+func TestSplitClusters(t *testing.T) {
+	cases := []struct {
+		By    string
+		Files map[string][]string
+	}{
+		{splitByCluster, map[string][]string{
+			"111111111111-eu-west-1-prod.yaml": {"eu-west-1-prod"},
+			"111111111111-us-east-1-prod.yaml": {"us-east-1-prod"},
+			"222222222222-eu-west-1-dev.yaml":  {"eu-west-1-dev"},
+		}},
+		{splitByAccount, map[string][]string{
+			"111111111111.yaml": {"eu-west-1-prod", "us-east-1-prod"},
+			"222222222222.yaml": {"eu-west-1-dev"},
+		}},
+		{splitByRegion, map[string][]string{
+			"eu-west-1.yaml": {"eu-west-1-dev", "eu-west-1-prod"},
+			"us-east-1.yaml": {"us-east-1-prod"},
+		}},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("split by %v", tt.By), func(t *testing.T) {
+			tmpl := template.Must(template.New("").Parse(splitGroups[tt.By].FileName))
+			files, err := splitClusters(newSplitClusters(t), tt.By, tmpl, "v1.2.3")
+			assert.NoError(t, err)
+			got := map[string][]string{}
+			for _, f := range files {
+				got[f.Path] = f.Contexts
+			}
+			assert.Equal(t, tt.Files, got)
+		})
+	}
+}
+
+func TestSplitClustersSameFile(t *testing.T) {
+
+	tmpl := template.Must(template.New("").Parse("{{.Name}}.yaml"))
+	// the same cluster name in two accounts
+	_, err := splitClusters(newSplitClusters(t), splitByCluster, tmpl, "")
+	assert.ErrorContains(t, err, "same file prod.yaml")
+
+	tmpl = template.Must(template.New("").Parse("/tmp/{{.Region}}.yaml"))
+	_, err = splitClusters(newSplitClusters(t), splitByRegion, tmpl, "")
+	assert.ErrorContains(t, err, "not in --output-dir")
+}
+
+func TestUpdateSplitFiles(t *testing.T) {
+	dir := t.TempDir()
+	setSplitFlags(t, splitByRegion, dir, "", "index.yaml")
+	tmpl, err := getSplitFileTemplate()
+	assert.NoError(t, err)
+
+	cmd, out := newTestCommand("")
+	assert.NoError(t, updateSplitFiles(cmd, newSplitClusters(t), tmpl))
+	assert.Contains(t, out.String(), "Wrote eu-west-1-dev, eu-west-1-prod to "+filepath.Join(dir, "eu-west-1.yaml"))
+
+	k, err := kubeconfig.LoadKubeconfig(filepath.Join(dir, "us-east-1.yaml"))
+	assert.NoError(t, err)
+	assert.Len(t, k.Entries(""), 1)
+	// the only cluster of the file is selected
+	data, err := os.ReadFile(filepath.Join(dir, "us-east-1.yaml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "current-context: us-east-1-prod")
+
+	data, err = os.ReadFile(filepath.Join(dir, "index.yaml"))
+	assert.NoError(t, err)
+	var index splitIndex
+	assert.NoError(t, yaml.Unmarshal(data, &index))
+	assert.Equal(t, splitIndexKind, index.Kind)
+	assert.Equal(t, []splitIndexFile{
+		{Path: "eu-west-1.yaml", Region: "eu-west-1", Contexts: []string{"eu-west-1-dev", "eu-west-1-prod"}},
+		{Path: "us-east-1.yaml", Region: "us-east-1", Contexts: []string{"us-east-1-prod"}},
+	}, index.Files)
+}
@@ -5,35 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
-)
-
-func Test_generateBackupNameNoConflict(t *testing.T) {
-	dir, err := os.MkdirTemp("", ".kube")
-	if err != nil {
-		t.Error(err.Error())
-	}
-	defer os.RemoveAll(dir)
-
-	kubeconfigPath := filepath.Join(dir, "kubeconfig")
-	backupKubeconfigPath := filepath.Join(dir, "kubeconfig.bak")
 
-	if err := os.WriteFile(kubeconfigPath, []byte("..."), 0600); err != nil {
-		t.Error(err.Error())
-	}
-
-	bName, err := backupKubeConfig(kubeconfigPath)
-	if err != nil {
-		t.Error(err.Error())
-	}
-
-	if !fileExists(backupKubeconfigPath) {
-		t.Errorf("Expecing %v to exist as backup of %v", backupKubeconfigPath, kubeconfigPath)
-	}
-
-	if bName != backupKubeconfigPath {
-		t.Errorf("Backup name is %v, expected %v", bName, backupKubeconfigPath)
-	}
-}
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
 
 func Test_fileExistsDir(t *testing.T) {
 	dir, err := os.MkdirTemp("", "dir")
@@ -75,3 +50,23 @@ func Test_fileExistsFile(t *testing.T) {
 		t.Errorf("Return false on file %v", path)
 	}
 }
+
+func TestNameClusters(t *testing.T) {
+	clusters := []*cluster.Cluster{
+		newEKSCluster("prod", "eu-west-1"),
+		newEKSCluster("prod", "us-east-1"),
+		newEKSCluster("dev", "us-east-1"),
+	}
+	// the same cluster found with another profile
+	clusters = append(clusters, clusters[2])
+
+	named := nameClusters(clusters, mustNameTemplate(t, "{{.Region}}-{{.Name}}"))
+	contexts := []string{}
+	for _, cls := range named {
+		contexts = append(contexts, cls.Context)
+	}
+	assert.Equal(t, []string{"eu-west-1-prod", "us-east-1-prod", "us-east-1-dev"}, contexts)
+
+	// the clusters without a name are skipped
+	assert.Empty(t, nameClusters(clusters, mustNameTemplate(t, `{{tag "missing"}}`)))
+}
@@ -0,0 +1,183 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"github.com/mateimicu/kdiscover/internal/backup"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	backupKubeconfig bool
+	backupDir        string
+	maxBackups       int
+)
+
+// getBackupStore returns the store for the backups of the kubeconfig
+func getBackupStore() *backup.Store {
+	dir := backupDir
+	if dir == "" {
+		dir = backup.DefaultDir(kubeconfigPath)
+	}
+	return backup.New(dir, kubeconfigPath)
+}
+
+// addBackupFlags adds the flags of the commands writing the kubeconfig
+func addBackupFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&backupKubeconfig, "backup-kubeconfig", true, "Backup cubeconfig before update")
+	addBackupStoreFlags(cmd.Flags())
+}
+
+func addBackupStoreFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&backupDir, "backup-dir", "",
+		fmt.Sprintf("Directory of the kubeconfig backups (default %v next to the kubeconfig)",
+			backup.DefaultDir("")))
+	flags.IntVar(&maxBackups, "max-backups", backup.DefaultMaxBackups,
+		"Number of kubeconfig backups to keep, the oldest ones are removed. Zero keeps all of them")
+}
+
+// backupKubeconfigFile saves the kubeconfig before it is changed and
+// removes the backups over --max-backups
+func backupKubeconfigFile(cmd *cobra.Command) error {
+	if !backupKubeconfig || !fileExists(kubeconfigPath) {
+		return nil
+	}
+	store := getBackupStore()
+	b, err := store.Create(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("Backup kubeconfig to %v\n", b.Path)
+
+	removed, err := store.Prune(maxBackups)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"dir": store.Dir,
+			"err": err.Error(),
+		}).Warn("Can't remove the old backups")
+		return nil
+	}
+	for _, b := range removed {
+		log.WithFields(log.Fields{
+			"backup": b.Path,
+		}).Info("Removed old backup")
+	}
+	return nil
+}
+
+func getBackupTable(backups []*backup.Backup, now time.Time) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"ID", "Created", "Age", "Size"})
+	for _, b := range backups {
+		tw.AppendRow(table.Row{
+			b.ID, b.CreatedAt.Local().Format(time.DateTime), now.Sub(b.CreatedAt).Round(time.Second), b.Size,
+		})
+	}
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Format.Header = text.FormatLower
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+func newBackupListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the backups of the kubeconfig, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			store := getBackupStore()
+			backups, err := store.List()
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Backups of %v in %v\n", kubeconfigPath, store.Dir)
+			cmd.Println(getBackupTable(backups, time.Now()))
+			return nil
+		},
+	}
+}
+
+func newBackupRestoreCommand() *cobra.Command {
+	restoreCommand := &cobra.Command{
+		Use:   "restore <id>",
+		Short: "Replace the kubeconfig with a backup, the current kubeconfig is backed up first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := getBackupStore().Get(args[0])
+			if err != nil {
+				return err
+			}
+			// read it before the rotation can remove it
+			data, err := os.ReadFile(b.Path)
+			if err != nil {
+				return err
+			}
+			if _, err := kubeconfig.LoadKubeconfig(b.Path); err != nil {
+				return fmt.Errorf("backup %v is not a valid kubeconfig: %w", b.ID, err)
+			}
+
+			unlock, err := kubeconfig.Lock(kubeconfigPath)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			if err := backupKubeconfigFile(cmd); err != nil {
+				return err
+			}
+			if err := kubeconfig.WriteLocked(kubeconfigPath, data); err != nil {
+				return err
+			}
+			cmd.Printf("Restored %v from backup %v\n", kubeconfigPath, b.ID)
+			return nil
+		},
+	}
+	restoreCommand.Flags().BoolVar(
+		&backupKubeconfig, "backup-kubeconfig", true, "Backup the current kubeconfig before restoring")
+	return restoreCommand
+}
+
+func newBackupDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <id>",
+		Short: "Show the changes a restore of the backup would make to the kubeconfig",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := getBackupStore().Get(args[0])
+			if err != nil {
+				return err
+			}
+			current, err := kubeconfig.LoadKubeconfig(kubeconfigPath)
+			if err != nil {
+				return err
+			}
+			saved, err := kubeconfig.LoadKubeconfig(b.Path)
+			if err != nil {
+				return err
+			}
+			return printKubeconfigDiff(cmd.OutOrStdout(), current, saved, kubeconfigPath, "backup "+b.ID)
+		},
+	}
+}
+
+func newBackupCommand() *cobra.Command {
+	backupCommand := &cobra.Command{
+		Use:   "backup",
+		Short: "Manage the backups of the kubeconfig taken before every update",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+
+	addBackupStoreFlags(backupCommand.PersistentFlags())
+	backupCommand.AddCommand(newBackupListCommand(), newBackupRestoreCommand(), newBackupDiffCommand())
+	return backupCommand
+}
@@ -0,0 +1,89 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/backup"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func runBackupCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"backup"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestBackupKubeconfigFile(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfigPath = filepath.Join(dir, "kubeconfig")
+	backupKubeconfig, backupDir, maxBackups = true, filepath.Join(dir, "backups"), 2
+	defer func() {
+		backupDir, maxBackups = "", backup.DefaultMaxBackups
+	}()
+
+	cmd, _ := newTestCommand("")
+	// nothing to backup yet
+	assert.NoError(t, backupKubeconfigFile(cmd))
+	assert.NoDirExists(t, backupDir)
+
+	assert.NoError(t, os.WriteFile(kubeconfigPath, []byte("v1"), 0o600))
+	store := getBackupStore()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, backupKubeconfigFile(cmd))
+	}
+	backups, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, backups, maxBackups)
+}
+
+func TestBackupListRestoreDiff(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kubeconfig")
+	clusters := cluster.GetMockClusters(2)
+
+	k := kubeconfig.New()
+	k.AddCluster(clusters[0], "first")
+	assert.NoError(t, k.Persist(path))
+	saved, err := backup.New(backup.DefaultDir(path), path).Create(path)
+	assert.NoError(t, err)
+	original, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	k.AddCluster(clusters[1], "second")
+	assert.NoError(t, k.Persist(path))
+
+	out, err := runBackupCommand(t, "list", "--kubeconfig-path", path)
+	assert.NoError(t, err)
+	assert.Contains(t, out, saved.ID)
+
+	out, err = runBackupCommand(t, "diff", saved.ID, "--kubeconfig-path", path)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "second")
+	assert.Contains(t, out, kubeconfig.ActionRemoved)
+
+	_, err = runBackupCommand(t, "restore", "missing", "--kubeconfig-path", path)
+	assert.ErrorIs(t, err, backup.ErrNotFound)
+
+	out, err = runBackupCommand(t, "restore", saved.ID, "--kubeconfig-path", path)
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Restored "+path)
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, original, current)
+
+	// the kubeconfig replaced by the restore is kept
+	backups, err := backup.New(backup.DefaultDir(path), path).List()
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
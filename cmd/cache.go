@@ -0,0 +1,103 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/jedib0t/go-pretty/text"
+	"github.com/mateimicu/kdiscover/internal/cache"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// getCache returns the cache from the default directory, nil if
+// the cache is disabled or can't be used
+func getCache(ttl time.Duration) *cache.Cache {
+	if ttl <= 0 {
+		return nil
+	}
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Warn("Can't find the cache directory, the cache is disabled")
+		return nil
+	}
+	return cache.New(dir, ttl)
+}
+
+func getCacheTable(c *cache.Cache, entries []*cache.Entry) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Provider", "Identity", "Region", "Clusters", "Age"})
+	rows := []table.Row{}
+	total := 0
+	for _, e := range entries {
+		rows = append(rows, table.Row{
+			e.Key.Provider, e.Key.Identity, e.Key.Region, len(e.Clusters), c.Age(e).Round(time.Second),
+		})
+		total += len(e.Clusters)
+	}
+	tw.AppendRows(rows)
+
+	tw.AppendFooter(table.Row{"", "", "Number of clusters", total})
+
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Format.Header = text.FormatLower
+	tw.Style().Format.Footer = text.FormatLower
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+func newCacheShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show the cached clusters",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c := getCache(cache.DefaultTTL)
+			if c == nil {
+				return nil
+			}
+			entries, err := c.Entries()
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Cache directory %v\n", c.Dir)
+			cmd.Println(getCacheTable(c, entries))
+			return nil
+		},
+	}
+}
+
+func newCacheClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all the cached clusters",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c := getCache(cache.DefaultTTL)
+			if c == nil {
+				return nil
+			}
+			count, err := c.Clear()
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Removed %v cache entries from %v\n", count, c.Dir)
+			return nil
+		},
+	}
+}
+
+func newCacheCommand() *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect the local cache of discovered clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+
+	cacheCommand.AddCommand(newCacheShowCommand(), newCacheClearCommand())
+	return cacheCommand
+}
@@ -0,0 +1,66 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cache"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func runCacheCommand(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"cache"}, args...))
+	assert.NoError(t, cmd.Execute())
+	return out.String()
+}
+
+func TestCacheShowClear(t *testing.T) {
+	c := getCache(time.Hour)
+	if !assert.NotNil(t, c) {
+		return
+	}
+	_, err := c.Clear()
+	assert.NoError(t, err)
+	key := cache.Key{Provider: "aws", Identity: "profile cache-test", Region: "eu-west-1"}
+	assert.NoError(t, c.Put(key, cluster.GetMockClusters(2)))
+
+	out := runCacheCommand(t, "show")
+	assert.Contains(t, out, "profile cache-test")
+	assert.Contains(t, out, "eu-west-1")
+
+	out = runCacheCommand(t, "clear")
+	assert.Contains(t, out, "Removed 1 cache entries")
+
+	out = runCacheCommand(t, "show")
+	assert.NotContains(t, out, "profile cache-test")
+}
+
+func TestPrintDataAge(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	fresh := cluster.GetMockClusters(2)
+	for _, cls := range fresh {
+		cls.DiscoveredAt = start.Add(time.Second)
+	}
+	cached := cluster.GetMockClusters(1)
+	cached[0].DiscoveredAt = start.Add(-5 * time.Minute)
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+
+	printDataAge(cmd, fresh, start)
+	assert.Empty(t, out.String())
+
+	printDataAge(cmd, append(fresh, cached...), start)
+	assert.Contains(t, out.String(), "5m0s ago")
+}
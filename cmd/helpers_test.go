@@ -0,0 +1,55 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newEKSCluster returns a cluster of the account 123456789012
+func newEKSCluster(name, region string) *cluster.Cluster {
+	cls := cluster.NewCluster()
+	cls.Provider = cluster.AWS
+	cls.Name = name
+	cls.Region = region
+	cls.ID = fmt.Sprintf("arn:aws:eks:%v:123456789012:cluster/%v", region, name)
+	cls.Endpoint = "https://" + name
+	cls.GenerateAuthInfo = func(cls *cluster.Cluster) *clientcmdapi.AuthInfo {
+		authInfo := clientcmdapi.NewAuthInfo()
+		authInfo.Exec = &clientcmdapi.ExecConfig{
+			Command: "aws",
+			Args:    []string{"eks", "get-token", "--cluster-name", cls.Name, "--region", cls.Region},
+		}
+		return authInfo
+	}
+	return cls
+}
+
+// newNamedClusters names the clusters with the context name template
+func newNamedClusters(t *testing.T, contextName string, clusters ...*cluster.Cluster) []namedCluster {
+	t.Helper()
+	return nameClusters(clusters, mustNameTemplate(t, contextName))
+}
+
+func mustNameTemplate(t *testing.T, value string) *cluster.NameTemplate {
+	t.Helper()
+	names, err := cluster.ParseNameTemplate(value, nil)
+	require.NoError(t, err)
+	return names
+}
+
+func newTestCommand(input string) (*cobra.Command, *bytes.Buffer) {
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	return cmd, &out
+}
@@ -0,0 +1,115 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/spf13/cobra"
+)
+
+var pruneYes bool
+
+func getPruneTable(entries []kubeconfig.Entry) string {
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Contexts", "Cluster"})
+	for _, entry := range entries {
+		tw.AppendRow(table.Row{strings.Join(entry.Contexts, ", "), entry.Key})
+	}
+	tw.SetStyle(table.StyleLight)
+	tw.Style().Options.SeparateColumns = false
+	return tw.Render()
+}
+
+// confirm asks a yes/no question on stdin, anything but yes is a no
+func confirm(cmd *cobra.Command, question string) bool {
+	cmd.Printf("%v [y/N]: ", question)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		// stdin is closed, there is nobody to answer
+		cmd.Println()
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// pruneKubeconfig removes the entries of the clusters that no longer
+// exist, after a confirmation if ask is set. It returns the number of
+// removed entries. In a dry-run the entries are removed from the
+// kubeconfig in memory, without asking, to show them in the diff.
+func pruneKubeconfig(
+	cmd *cobra.Command, k *kubeconfig.Kubeconfig, discovery *aws.Discovery, ask, dryRun bool,
+) int {
+	stale := discovery.Stale(k.Entries(""))
+	if len(stale) == 0 {
+		cmd.Println("No stale kubeconfig entries")
+		return 0
+	}
+
+	cmd.Printf("Found %v kubeconfig entries for clusters that no longer exist\n", len(stale))
+	cmd.Println(getPruneTable(stale))
+	if ask && !dryRun && !confirm(cmd, "Remove them?") {
+		cmd.Println("Nothing removed")
+		return 0
+	}
+	for _, entry := range stale {
+		k.Remove(entry)
+	}
+	if dryRun {
+		cmd.Printf("Would remove %v kubeconfig entries\n", len(stale))
+	} else {
+		cmd.Printf("Removed %v kubeconfig entries\n", len(stale))
+	}
+	return len(stale)
+}
+
+func newPruneCommand() *cobra.Command {
+	pruneCommand := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the kubeconfig entries of deleted EKS clusters",
+		Long: `Search for EKS clusters and remove the clusters, users and contexts
+written by kdiscover for clusters that no longer exist. Only the regions
+searched without errors, with the same profile and role, are pruned.`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return loadAWSOptions(cmd.Context())
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			discovery, failures, err := discoverEKSClusters(cmd, true)
+			if err != nil {
+				return err
+			}
+			unlock, err := kubeconfig.Lock(kubeconfigPath)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			k, err := kubeconfig.LoadKubeconfig(kubeconfigPath)
+			if err != nil {
+				return err
+			}
+
+			if pruneKubeconfig(cmd, k, discovery, !pruneYes, false) != 0 {
+				if err := backupKubeconfigFile(cmd); err != nil {
+					return err
+				}
+				if err := k.PersistLocked(kubeconfigPath); err != nil {
+					return err
+				}
+			}
+			return reportFailures(cmd, failures)
+		},
+	}
+
+	addAWSFlags(pruneCommand)
+	pruneCommand.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Remove the entries without asking for confirmation")
+	addBackupFlags(pruneCommand)
+
+	return pruneCommand
+}
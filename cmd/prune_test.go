@@ -0,0 +1,97 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirm(t *testing.T) {
+	tts := []struct {
+		Input    string
+		Expected bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"yes", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"maybe\n", false},
+	}
+	for _, tt := range tts {
+		testname := fmt.Sprintf("input %q", tt.Input)
+		t.Run(testname, func(t *testing.T) {
+			cmd, out := newTestCommand(tt.Input)
+			assert.Equal(t, tt.Expected, confirm(cmd, "Remove?"))
+			assert.Contains(t, out.String(), "Remove? [y/N]")
+		})
+	}
+}
+
+func TestPruneKubeconfig(t *testing.T) {
+	tts := []struct {
+		Input   string
+		Yes     bool
+		DryRun  bool
+		Removed int
+		Message string
+	}{
+		{"y\n", false, false, 1, "Removed 1 kubeconfig entries"},
+		{"n\n", false, false, 0, "Nothing removed"},
+		{"", true, false, 1, "Removed 1 kubeconfig entries"},
+		// nothing is removed from the file so there is no need to ask
+		{"", false, true, 1, "Would remove 1 kubeconfig entries"},
+	}
+	for _, tt := range tts {
+		testname := fmt.Sprintf("input %q yes %v dry-run %v", tt.Input, tt.Yes, tt.DryRun)
+		t.Run(testname, func(t *testing.T) {
+			existing := newEKSCluster("existing", "eu-west-1")
+			deleted := newEKSCluster("deleted", "eu-west-1")
+			k := kubeconfig.New()
+			k.AddCluster(existing, existing.Name)
+			k.AddCluster(deleted, deleted.Name)
+			discovery := &aws.Discovery{
+				Clusters: []*cluster.Cluster{existing},
+				Searched: []aws.Scope{{Region: "eu-west-1"}},
+			}
+
+			cmd, out := newTestCommand(tt.Input)
+			assert.Equal(t, tt.Removed, pruneKubeconfig(cmd, k, discovery, !tt.Yes, tt.DryRun))
+			assert.Contains(t, out.String(), deleted.GetUniqueID())
+			assert.Contains(t, out.String(), tt.Message)
+			assert.Equal(t, !tt.Yes && !tt.DryRun, strings.Contains(out.String(), "[y/N]"))
+			assert.True(t, k.IsExported(existing))
+			assert.Equal(t, tt.Removed == 0, k.IsExported(deleted))
+		})
+	}
+}
+
+func TestPruneWithoutCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	k := kubeconfig.New()
+	k.AddCluster(newEKSCluster("deleted", "eu-west-1"), "deleted")
+	require.NoError(t, k.Persist(path))
+
+	var out bytes.Buffer
+	cmd := NewRootCommand("", "", "", "kdiscover")
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"prune", "--yes", "--aws-regions", "eu-west-1", "--kubeconfig-path", path})
+	require.NoError(t, cmd.Execute())
+
+	// the region failed so nothing is pruned
+	assert.Contains(t, out.String(), "No stale kubeconfig entries")
+	k, err := kubeconfig.LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, k.Entries(""), 1)
+}
@@ -2,11 +2,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/mateimicu/kdiscover/internal/kubeconfig"
 	"github.com/spf13/cobra"
@@ -27,11 +30,23 @@ var (
 		"debug": log.DebugLevel,
 		"trace": log.TraceLevel,
 	}
-	kubeconfigPath string
-	logLevel       string
+	// kubeconfigPath is the file written, it is resolved from the flags
+	// before running the commands
+	kubeconfigPath        string
+	kubeconfigDestination string
+	// kubeconfigPaths are merged to check if a cluster is exported
+	kubeconfigPaths []string
+	logLevel        string
+	// kdiscoverVersion is recorded in the kubeconfig entries
+	kdiscoverVersion string
+	// kdiscoverCommand is the name of the binary, kubectl-discover when it
+	// is installed as a kubectl plugin
+	kdiscoverCommand string
 )
 
 func NewRootCommand(version, commit, date, commandPrefix string) *cobra.Command {
+	kdiscoverVersion = version
+	kdiscoverCommand = strings.ReplaceAll(commandPrefix, " ", "-")
 	rootCmd := &cobra.Command{
 		Use:   commandPrefix,
 		Short: "Discover all EKS clusters on an account.",
@@ -39,18 +54,11 @@ func NewRootCommand(version, commit, date, commandPrefix string) *cobra.Command
 all regions on an AWS account and try to find all EKS clsuters.
 It will try to upgrade the kube-config for each cluster.`,
 
-		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
-			if logLevel == "none" {
-				log.SetOutput(io.Discard)
-				return nil
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := setLogLevel(); err != nil {
+				return err
 			}
-
-			if v, ok := loggingLevels[logLevel]; ok {
-				log.SetLevel(v)
-				return nil
-			}
-
-			return fmt.Errorf("can't find logging level %v", logLevel)
+			return resolveKubeconfig(cmd)
 		},
 
 		RunE: func(cmd *cobra.Command, _ []string) error {
@@ -68,10 +76,22 @@ It will try to upgrade the kube-config for each cluster.`,
 	rootCmd.PersistentFlags().StringVar(
 		&kubeconfigPath,
 		"kubeconfig-path",
-		kubeconfig.GetDefaultKubeconfigPath(),
-		"Path to the kubeconfig to work with")
+		"",
+		"Path to the kubeconfig to work with, replaces the files of $KUBECONFIG")
+	rootCmd.PersistentFlags().StringVar(
+		&kubeconfigDestination,
+		"kubeconfig-destination",
+		kubeconfig.DestinationFirst,
+		fmt.Sprintf("File the clusters are written to when $KUBECONFIG is used: %v (the first existing file), "+
+			"%v (%v) or a path",
+			kubeconfig.DestinationFirst, kubeconfig.DestinationDedicated,
+			kubeconfig.Destination(kubeconfig.DestinationDedicated, nil)))
 
 	rootCmd.AddCommand(newAWSCommand())
+	rootCmd.AddCommand(newCacheCommand())
+	rootCmd.AddCommand(newPruneCommand())
+	rootCmd.AddCommand(newBackupCommand())
+	rootCmd.AddCommand(newTokenCommand())
 	rootCmd.AddCommand(newVersionCommand(version, commit, date))
 	return rootCmd
 }
@@ -84,12 +104,45 @@ func Execute(version, commit, date string) {
 	}
 	rootCmd := NewRootCommand(version, commit, date, prefix)
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	// cancel the in-flight calls on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		// stdout is kept for the output, like the ExecCredential of token
+		fmt.Fprintln(rootCmd.ErrOrStderr(), err)
 		os.Exit(errorExitCode)
 	}
 }
 
+func setLogLevel() error {
+	if logLevel == "none" {
+		log.SetOutput(io.Discard)
+		return nil
+	}
+
+	if v, ok := loggingLevels[logLevel]; ok {
+		log.SetLevel(v)
+		return nil
+	}
+
+	return fmt.Errorf("can't find logging level %v", logLevel)
+}
+
+// resolveKubeconfig finds the kubeconfig files like kubectl, from
+// --kubeconfig-path or $KUBECONFIG, and the one to write
+func resolveKubeconfig(cmd *cobra.Command) error {
+	if kubeconfigPath != "" && cmd.Flags().Changed("kubeconfig-destination") {
+		return fmt.Errorf("--kubeconfig-path and --kubeconfig-destination can't be used together")
+	}
+	kubeconfigPaths = kubeconfig.Paths(kubeconfigPath)
+	kubeconfigPath = kubeconfig.Destination(kubeconfigDestination, kubeconfigPaths)
+	if kubeconfigPath == "" {
+		return fmt.Errorf("empty --kubeconfig-destination")
+	}
+	return nil
+}
+
 func getAllLogglingLevels() []string {
 	keys := make([]string, 0, len(loggingLevels))
 	for k := range loggingLevels {
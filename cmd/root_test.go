@@ -10,8 +10,31 @@ import (
 	"testing"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+func TestMain(m *testing.M) {
+	// without credentials the AWS SDK will retry the EC2 metadata
+	// endpoint for every region, which is slow outside of EC2
+	if err := os.Setenv("AWS_EC2_METADATA_DISABLED", "true"); err != nil {
+		fmt.Println(err)
+		os.Exit(errorExitCode)
+	}
+	// keep the discovery cache of the tests away from the user cache
+	cacheDir, err := os.MkdirTemp("", "kdiscover-cache")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(errorExitCode)
+	}
+	if err := os.Setenv("XDG_CACHE_HOME", cacheDir); err != nil {
+		fmt.Println(err)
+		os.Exit(errorExitCode)
+	}
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
 var basicCommands = []struct {
 	cmd     []string
 	context string
@@ -104,3 +127,50 @@ func Test_getAllLogglingLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	if err := os.WriteFile(second, []byte{}, 0o600); err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, first+string(os.PathListSeparator)+second)
+
+	cases := []struct {
+		Args        []string
+		Destination string
+		Err         bool
+	}{
+		{[]string{}, second, false},
+		{
+			[]string{"--kubeconfig-destination", "dedicated"},
+			filepath.Join(clientcmd.RecommendedConfigDir, "kdiscover.yaml"), false,
+		},
+		{[]string{"--kubeconfig-destination", first}, first, false},
+		{[]string{"--kubeconfig-path", first}, first, false},
+		{[]string{"--kubeconfig-path", first, "--kubeconfig-destination", "first"}, "", true},
+		{[]string{"--kubeconfig-destination", ""}, "", true},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("resolve %v", tt.Args), func(t *testing.T) {
+			cmd := NewRootCommand("", "", "", "kdiscover")
+			buf := new(strings.Builder)
+			cmd.SetOut(buf)
+			cmd.SetErr(buf)
+			cmd.SetArgs(append([]string{"backup", "list"}, tt.Args...))
+			err := cmd.Execute()
+			if tt.Err {
+				if err == nil {
+					t.Errorf("Expected an error for %v", tt.Args)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err.Error())
+			}
+			if !strings.Contains(buf.String(), "Backups of "+tt.Destination+" in") {
+				t.Errorf("Expected %v as destination, got %v", tt.Destination, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+)
+
+const (
+	execInfoEnvVar           = "KUBERNETES_EXEC_INFO"
+	defaultExecCredentialAPI = "client.authentication.k8s.io/v1beta1"
+)
+
+var (
+	tokenCluster string
+	tokenRegion  string
+	tokenRoleARN string
+)
+
+// execCredentialVersion is the API version kubectl asked for in
+// KUBERNETES_EXEC_INFO, the older clients don't set it
+func execCredentialVersion(execInfo string) string {
+	info := metav1.TypeMeta{}
+	if err := json.Unmarshal([]byte(execInfo), &info); err != nil || info.APIVersion == "" {
+		return defaultExecCredentialAPI
+	}
+	return info.APIVersion
+}
+
+// printExecCredential writes the token for kubectl, the status has the
+// same fields in v1beta1 and v1
+func printExecCredential(w io.Writer, token aws.Token, apiVersion string) error {
+	expiration := metav1.NewTime(token.Expiration)
+	credential := clientauthv1beta1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: "ExecCredential"},
+		Status: &clientauthv1beta1.ExecCredentialStatus{
+			ExpirationTimestamp: &expiration,
+			Token:               token.Token,
+		},
+	}
+	return json.NewEncoder(w).Encode(credential)
+}
+
+func newTokenCommand() *cobra.Command {
+	tokenCommand := &cobra.Command{
+		Use:   "token",
+		Short: "Print a token for an EKS cluster, used by the kubeconfig users written with --auth=kdiscover",
+		Long: `Generate the token of an EKS cluster, like 'aws eks get-token', and print it
+as an ExecCredential. The credentials come from the default credential chain,
+use AWS_PROFILE to pick a profile.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if tokenRoleARN != "" {
+				if err := aws.ValidateRoleARN(tokenRoleARN); err != nil {
+					return err
+				}
+			}
+			identity := aws.Identity{RoleARN: tokenRoleARN}
+			token, err := aws.GetToken(cmd.Context(), identity, tokenCluster, tokenRegion)
+			if err != nil {
+				return fmt.Errorf("can't generate a token for %v: %w", tokenCluster, err)
+			}
+			return printExecCredential(cmd.OutOrStdout(), token, execCredentialVersion(os.Getenv(execInfoEnvVar)))
+		},
+	}
+
+	tokenCommand.Flags().StringVar(&tokenCluster, "cluster", "", "Name of the EKS cluster")
+	tokenCommand.Flags().StringVar(&tokenRegion, "region", "", "Region of the EKS cluster")
+	tokenCommand.Flags().StringVar(&tokenRoleARN, "role-arn", "", "IAM role to assume before generating the token")
+	_ = tokenCommand.MarkFlagRequired("cluster")
+	_ = tokenCommand.MarkFlagRequired("region")
+	return tokenCommand
+}
@@ -0,0 +1,52 @@
+// Package cmd offers CLI functionality
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/aws"
+	"github.com/stretchr/testify/assert"
+	clientauthv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+)
+
+func TestExecCredentialVersion(t *testing.T) {
+	cases := []struct {
+		ExecInfo, Expected string
+	}{
+		{"", "client.authentication.k8s.io/v1beta1"},
+		{"not json", "client.authentication.k8s.io/v1beta1"},
+		{`{"kind":"ExecCredential","apiVersion":"client.authentication.k8s.io/v1","spec":{"interactive":false}}`,
+			"client.authentication.k8s.io/v1"},
+	}
+	for _, tt := range cases {
+		assert.Equal(t, tt.Expected, execCredentialVersion(tt.ExecInfo))
+	}
+}
+
+func TestPrintExecCredential(t *testing.T) {
+	expiration := time.Date(2024, time.January, 1, 12, 14, 0, 0, time.UTC)
+	buf := new(strings.Builder)
+	token := aws.Token{Token: "k8s-aws-v1.token", Expiration: expiration}
+	assert.NoError(t, printExecCredential(buf, token, "client.authentication.k8s.io/v1"))
+
+	credential := clientauthv1.ExecCredential{}
+	assert.NoError(t, json.Unmarshal([]byte(buf.String()), &credential))
+	assert.Equal(t, "ExecCredential", credential.Kind)
+	assert.Equal(t, "client.authentication.k8s.io/v1", credential.APIVersion)
+	if assert.NotNil(t, credential.Status) {
+		assert.Equal(t, "k8s-aws-v1.token", credential.Status.Token)
+		assert.True(t, expiration.Equal(credential.Status.ExpirationTimestamp.Time))
+	}
+}
+
+func TestTokenRequiresCluster(t *testing.T) {
+	cmd := NewRootCommand("mock-version", "mock-commit", "mock-date", "kdiscover")
+	buf := new(strings.Builder)
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	cmd.SetArgs([]string{"token", "--region", "eu-west-1"})
+	assert.ErrorContains(t, cmd.Execute(), `"cluster" not set`)
+}
@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const defaultRoleSessionName = "kdiscover"
+
+// Identity describes the credentials used to query the AWS APIs.
+// An empty Profile means the default credential chain and an empty
+// RoleARN means that no role is assumed on top of it.
+// AccountID and AccountName are known only for identities derived
+// from an AWS Organization.
+type Identity struct {
+	Profile         string
+	RoleARN         string
+	RoleSessionName string
+	RoleExternalID  string
+	AccountID       string
+	AccountName     string
+}
+
+func (i Identity) String() string {
+	switch {
+	case i.Profile != "" && i.RoleARN != "":
+		return fmt.Sprintf("profile %v assuming %v", i.Profile, i.RoleARN)
+	case i.RoleARN != "":
+		return fmt.Sprintf("assuming %v", i.RoleARN)
+	case i.Profile != "":
+		return fmt.Sprintf("profile %v", i.Profile)
+	}
+	return "default credentials"
+}
+
+// newConfig creates the SDK config for the identity. The region is only
+// used for the calls needed to obtain the credentials (like sts:AssumeRole),
+// clients should set their own region.
+func newConfig(ctx context.Context, identity Identity, region string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(region),
+		config.WithSharedConfigProfile(identity.Profile),
+	)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if identity.RoleARN == "" {
+		return cfg, nil
+	}
+
+	// the assumed credentials are cached and shared by all the clients
+	// created from this config
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), identity.RoleARN,
+		func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = defaultRoleSessionName
+			if identity.RoleSessionName != "" {
+				o.RoleSessionName = identity.RoleSessionName
+			}
+			if identity.RoleExternalID != "" {
+				o.ExternalID = aws.String(identity.RoleExternalID)
+			}
+		})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg, nil
+}
@@ -2,131 +2,249 @@
 package aws
 
 import (
+	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"         //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
-	"github.com/aws/aws-sdk-go/aws/awserr"  //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
-	"github.com/aws/aws-sdk-go/aws/session" //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
-	"github.com/aws/aws-sdk-go/service/eks" //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
-	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/mateimicu/kdiscover/internal/cluster"
 	log "github.com/sirupsen/logrus"
 )
 
+// EKSAPI is the subset of the EKS API used to discover clusters
+type EKSAPI interface {
+	eks.ListClustersAPIClient
+	eks.DescribeClusterAPIClient
+}
+
 type EKSClient struct {
-	EKS    eksiface.EKSAPI
-	Region string
+	EKS      EKSAPI
+	Region   string
+	Identity Identity
+	// Limiter bounds the parallel DescribeCluster calls, if nil the
+	// clusters are described one by one
+	Limiter Limiter
+	Backoff Backoff
 }
 
 func (c *EKSClient) String() string {
-	return fmt.Sprintf("EKS Client for region %v", c.Region)
+	return fmt.Sprintf("EKS Client for region %v (%v)", c.Region, c.Identity)
 }
 
-// TODO(mmicu):
-// - test GetClusters function
-// - use assert library in others tests also
-func (c *EKSClient) GetClusters(ch chan<- *cluster.Cluster) {
-	input := &eks.ListClustersInput{}
+// GetClusters sends every cluster from the region on the channel. Clusters
+// that can't be described are skipped and returned in a *DiscoveryError,
+// if the region can't be listed a *Failure is returned.
+func (c *EKSClient) GetClusters(ctx context.Context, ch chan<- *cluster.Cluster) error {
+	defer close(ch)
+	metrics := &regionMetrics{}
+	defer func() {
+		log.WithFields(metrics.fields()).WithField("svc", c.String()).Debug("Region API calls")
+	}()
+	ctx = withRegionMetrics(ctx, metrics)
 
-	err := c.EKS.ListClustersPages(input,
-		func(page *eks.ListClustersOutput, lastPage bool) bool {
+	limiter := c.Limiter
+	if limiter == nil {
+		limiter = NewLimiter(1)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	paginator := eks.NewListClustersPaginator(c.EKS, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := c.nextPage(ctx, paginator, metrics)
+		if err != nil {
 			log.WithFields(log.Fields{
-				"svc":  c.String(),
-				"page": page.GoString(),
-			}).Debug("Parse page")
-			for _, cluster := range page.Clusters {
-				log.WithFields(log.Fields{
-					"svc":     c.String(),
-					"cluster": *cluster,
-				}).Debug("Found cluster")
-				if cls, err := c.detailCluster(*cluster); err == nil {
-					ch <- cls
-				} else {
-					log.WithFields(log.Fields{
-						"svc":     c.String(),
-						"cluster": *cluster,
-						"err":     err,
-					}).Warn("Can't get details on the cluster")
-				}
-			}
+				"err": err,
+				"svc": c.String(),
+			}).Warn("Can't list clusters")
+			wg.Wait()
+			return newDiscoveryError(append(failures, c.failure("", err)))
+		}
 
-			if lastPage {
-				log.WithFields(log.Fields{
-					"svc": c.String(),
-				}).Debug("hit last page")
-				return false
+		for _, name := range page.Clusters {
+			if limiter.acquire(ctx) != nil {
+				break
 			}
-			return true
-		})
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				defer limiter.release()
+				if err := c.sendCluster(ctx, name, ch, metrics); err != nil {
+					mu.Lock()
+					failures = append(failures, err)
+					mu.Unlock()
+				}
+			}(name)
+		}
+	}
+	wg.Wait()
 
+	if ctx.Err() != nil {
+		return newDiscoveryError(append(failures, c.failure("", ctx.Err())))
+	}
+	log.WithFields(log.Fields{
+		"svc": c.String(),
+	}).Debug("hit last page")
+	return newDiscoveryError(failures)
+}
+
+func (c *EKSClient) nextPage(
+	ctx context.Context, paginator *eks.ListClustersPaginator, metrics *regionMetrics,
+) (*eks.ListClustersOutput, error) {
+	var page *eks.ListClustersOutput
+	// a failed call doesn't advance the paginator so it can be retried
+	err := c.Backoff.retry(ctx, metrics, func() error {
+		metrics.listCalls.Add(1)
+		var err error
+		page, err = paginator.NextPage(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"svc":      c.String(),
+		"clusters": page.Clusters,
+	}).Debug("Parse page")
+	return page, nil
+}
+
+// sendCluster describes the cluster and sends it on the channel, the
+// cancellation of the context is handled by the caller
+func (c *EKSClient) sendCluster(
+	ctx context.Context, name string, ch chan<- *cluster.Cluster, metrics *regionMetrics,
+) *Failure {
+	log.WithFields(log.Fields{
+		"svc":     c.String(),
+		"cluster": name,
+	}).Debug("Found cluster")
+
+	var cls *cluster.Cluster
+	err := c.Backoff.retry(ctx, metrics, func() error {
+		metrics.describeCalls.Add(1)
+		var err error
+		cls, err = c.detailCluster(ctx, name)
+		return err
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
-			"err": err,
-			"svc": c.String(),
-		}).Warn("Can't list clusters")
+			"svc":     c.String(),
+			"cluster": name,
+			"err":     err,
+		}).Warn("Can't get details on the cluster")
+		return c.failure(name, err)
 	}
 
-	close(ch)
+	select {
+	case ch <- cls:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (c *EKSClient) failure(clusterName string, err error) *Failure {
+	return &Failure{
+		Region:   c.Region,
+		Identity: c.Identity,
+		Cluster:  clusterName,
+		Err:      err,
+	}
 }
 
-func (c *EKSClient) detailCluster(cName string) (*cluster.Cluster, error) {
+func (c *EKSClient) detailCluster(ctx context.Context, cName string) (*cluster.Cluster, error) {
 	input := &eks.DescribeClusterInput{
 		Name: aws.String(cName),
 	}
 
-	result, err := c.EKS.DescribeCluster(input)
+	result, err := c.EKS.DescribeCluster(ctx, input)
 	if err != nil {
-		// TODO(mmicu): handle errors better here
-		if aerr, ok := err.(awserr.Error); ok {
-			log.Warn(aerr.Error())
-		} else {
-			log.Warn(err.Error())
-		}
-		msg := fmt.Sprintf("Can't fetch more details for the cluster %v", cName)
 		log.WithFields(log.Fields{
 			"cluster-name": cName,
 			"svc":          c.String(),
-		}).Warn(msg)
-		return nil, errors.New(msg)
+			"err":          err,
+		}).Debug("Can't fetch more details for the cluster")
+		return nil, fmt.Errorf("can't describe the cluster: %w", err)
 	}
 
-	certificatAuthorityData, err := base64.StdEncoding.DecodeString(*result.Cluster.CertificateAuthority.Data)
+	certificatAuthorityData, err := base64.StdEncoding.DecodeString(aws.ToString(result.Cluster.CertificateAuthority.Data))
 	if err != nil {
 		log.WithFields(log.Fields{
-			"cluster-name":               *result.Cluster.Name,
-			"arn":                        *result.Cluster.Arn,
-			"certificate-authority-data": *result.Cluster.CertificateAuthority.Data,
+			"cluster-name":               aws.ToString(result.Cluster.Name),
+			"arn":                        aws.ToString(result.Cluster.Arn),
+			"certificate-authority-data": aws.ToString(result.Cluster.CertificateAuthority.Data),
 			"svc":                        c.String(),
 		}).Error("Can't decode the Certificate Authority Data")
-		return nil, err
+		return nil, fmt.Errorf("can't decode the certificate authority data: %w", err)
 	}
 
 	cls := cluster.NewCluster()
-	cls.Name = *result.Cluster.Name
-	cls.ID = *result.Cluster.Arn
-	cls.Endpoint = *result.Cluster.Endpoint
+	cls.Provider = cluster.AWS
+	cls.Name = aws.ToString(result.Cluster.Name)
+	cls.ID = aws.ToString(result.Cluster.Arn)
+	cls.Endpoint = aws.ToString(result.Cluster.Endpoint)
 	cls.CertificateAuthorityData = string(certificatAuthorityData)
-	cls.Status = *result.Cluster.Status
+	cls.Status = string(result.Cluster.Status)
 	cls.Region = c.Region
+	cls.Profile = c.Identity.Profile
+	cls.RoleARN = c.Identity.RoleARN
+	cls.AccountID = c.Identity.AccountID
+	cls.AccountName = c.Identity.AccountName
+	cls.DiscoveredAt = time.Now()
+	setMetadata(cls, result.Cluster)
+	if cls.AccountID == "" {
+		cls.AccountID = accountFromARN(cls.ID)
+	}
 
 	return cls, nil
 }
 
-func NewEKS(region string) (*EKSClient, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(region),
-	})
+// setMetadata copies the optional details returned by DescribeCluster
+func setMetadata(cls *cluster.Cluster, details *types.Cluster) {
+	cls.ARN = aws.ToString(details.Arn)
+	cls.KubernetesVersion = aws.ToString(details.Version)
+	cls.PlatformVersion = aws.ToString(details.PlatformVersion)
+	cls.Tags = details.Tags
+	cls.CreatedAt = aws.ToTime(details.CreatedAt)
+	if vpc := details.ResourcesVpcConfig; vpc != nil {
+		cls.EndpointPublicAccess = vpc.EndpointPublicAccess
+		cls.EndpointPrivateAccess = vpc.EndpointPrivateAccess
+		cls.VpcID = aws.ToString(vpc.VpcId)
+	}
+	if details.Identity != nil && details.Identity.Oidc != nil {
+		cls.OIDCIssuer = aws.ToString(details.Identity.Oidc.Issuer)
+	}
+}
+
+func accountFromARN(value string) string {
+	a, err := arn.Parse(value)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"region": region,
-			"error":  err.Error(),
-		}).Error("Failed to create AWS SDK session")
-		return nil, err
+		return ""
 	}
+	return a.AccountID
+}
+
+// NewEKS creates a client for the given region that will use the
+// credentials from the config
+func NewEKS(cfg aws.Config, region string, identity Identity) *EKSClient {
 	return &EKSClient{
-		EKS:    eks.New(sess),
-		Region: region,
-	}, nil
+		EKS: eks.NewFromConfig(cfg, func(o *eks.Options) {
+			o.Region = region
+			o.Retryer = newCountingRetryer(o.Retryer)
+		}),
+		Region:   region,
+		Identity: identity,
+		Limiter:  NewLimiter(DefaultConcurrency),
+		Backoff:  defaultBackoff(),
+	}
 }
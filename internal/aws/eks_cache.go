@@ -0,0 +1,113 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/mateimicu/kdiscover/internal/cache"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+const cacheProvider = "aws"
+
+// STSAPI is the subset of the STS API used to identify the credentials
+type STSAPI interface {
+	GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (
+		*sts.GetCallerIdentityOutput, error)
+}
+
+// callerARN returns the ARN of the credentials of the config. The
+// identity alone does not tell them apart, the default credentials
+// depend on AWS_PROFILE, the environment or the instance.
+func callerARN(ctx context.Context, api STSAPI) (string, error) {
+	out, err := api.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Arn), nil
+}
+
+// cachedGetter serves the clusters of a region from the cache and
+// stores the result of complete searches
+type cachedGetter struct {
+	getter   ClusterGetter
+	cache    *cache.Cache
+	refresh  bool
+	region   string
+	identity Identity
+	// caller is the ARN of the credentials used by the identity
+	caller string
+}
+
+func newCachedGetter(c *EKSClient, store *cache.Cache, refresh bool, caller string) *cachedGetter {
+	return &cachedGetter{
+		getter:   c,
+		cache:    store,
+		refresh:  refresh,
+		region:   c.Region,
+		identity: c.Identity,
+		caller:   caller,
+	}
+}
+
+func (g *cachedGetter) key() cache.Key {
+	return cache.Key{
+		Provider: cacheProvider,
+		Identity: fmt.Sprintf("%v (%v)", g.identity, g.caller),
+		Region:   g.region,
+	}
+}
+
+func (g *cachedGetter) GetClusters(ctx context.Context, ch chan<- *cluster.Cluster) error {
+	if !g.refresh {
+		if clusters, ok := g.cache.Get(g.key()); ok {
+			log.WithFields(log.Fields{
+				"region":   g.region,
+				"identity": g.identity.String(),
+			}).Debug("Use cached clusters")
+			return g.sendCached(ctx, clusters, ch)
+		}
+	}
+
+	inner := make(chan *cluster.Cluster)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.getter.GetClusters(ctx, inner)
+	}()
+
+	found := []*cluster.Cluster{}
+	for cls := range inner {
+		// copy before sending, the receiver adds the auth config
+		stored := *cls
+		found = append(found, &stored)
+		ch <- cls
+	}
+	close(ch)
+
+	// partial results are not cached
+	err := <-errs
+	if err == nil {
+		if err := g.cache.Put(g.key(), found); err != nil {
+			log.WithFields(log.Fields{
+				"region": g.region,
+				"err":    err,
+			}).Warn("Can't cache the clusters")
+		}
+	}
+	return err
+}
+
+func (g *cachedGetter) sendCached(ctx context.Context, clusters []*cluster.Cluster, ch chan<- *cluster.Cluster) error {
+	defer close(ch)
+	for _, cls := range clusters {
+		select {
+		case ch <- cls:
+		case <-ctx.Done():
+			return &Failure{Region: g.region, Identity: g.identity, Err: ctx.Err()}
+		}
+	}
+	return nil
+}
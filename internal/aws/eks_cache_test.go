@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/mateimicu/kdiscover/internal/cache"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingGetter counts the calls and can fail after sending the clusters
+type countingGetter struct {
+	Clusters []*cluster.Cluster
+	Err      error
+	Calls    int
+}
+
+func (g *countingGetter) GetClusters(_ context.Context, ch chan<- *cluster.Cluster) error {
+	defer close(ch)
+	g.Calls++
+	for _, cls := range g.Clusters {
+		ch <- cls
+	}
+	return g.Err
+}
+
+func collectCached(t *testing.T, g *cachedGetter) ([]*cluster.Cluster, error) {
+	t.Helper()
+	ch := make(chan *cluster.Cluster)
+	errs := make(chan error, 1)
+	go func() { errs <- g.GetClusters(context.Background(), ch) }()
+	clusters := []*cluster.Cluster{}
+	for cls := range ch {
+		clusters = append(clusters, cls)
+	}
+	return clusters, <-errs
+}
+
+func TestCachedGetter(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Err           error
+		Refresh       bool
+		ExpectedCalls int
+	}{
+		{nil, false, 1},
+		{nil, true, 2},
+		// partial results are not cached
+		{errors.New("boom"), false, 2},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("err %v refresh %v", tt.Err, tt.Refresh)
+		t.Run(testname, func(t *testing.T) {
+			store := cache.New(filepath.Join(t.TempDir(), "kdiscover"), time.Hour)
+			inner := &countingGetter{Clusters: cluster.GetMockClusters(3), Err: tt.Err}
+			g := &cachedGetter{
+				getter:   inner,
+				cache:    store,
+				refresh:  tt.Refresh,
+				region:   "eu-west-1",
+				identity: Identity{Profile: "dev"},
+				caller:   "arn:aws:iam::123456789012:user/dev",
+			}
+
+			for i := 0; i < 2; i++ {
+				clusters, err := collectCached(t, g)
+				assert.Equal(t, tt.Err, err)
+				assert.Len(t, clusters, len(inner.Clusters))
+			}
+			assert.Equal(t, tt.ExpectedCalls, inner.Calls)
+		})
+	}
+}
+
+type mockSTSClient struct {
+	ARN string
+}
+
+func (c *mockSTSClient) GetCallerIdentity(
+	_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options),
+) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(c.ARN)}, nil
+}
+
+func TestCachedGetterDefaultCredentials(t *testing.T) {
+	t.Parallel()
+	store := cache.New(filepath.Join(t.TempDir(), "kdiscover"), time.Hour)
+	// the default credentials of AWS_PROFILE=dev and AWS_PROFILE=prod
+	getters := map[string]*countingGetter{
+		"arn:aws:iam::111111111111:user/dev":  {Clusters: cluster.GetMockClusters(1)},
+		"arn:aws:iam::222222222222:user/prod": {Clusters: cluster.GetMockClusters(2)},
+	}
+	for i := 0; i < 2; i++ {
+		for arn, inner := range getters {
+			caller, err := callerARN(context.Background(), &mockSTSClient{ARN: arn})
+			assert.NoError(t, err)
+			g := &cachedGetter{getter: inner, cache: store, region: "eu-west-1", identity: Identity{}, caller: caller}
+			clusters, err := collectCached(t, g)
+			assert.NoError(t, err)
+			assert.Len(t, clusters, len(inner.Clusters), arn)
+		}
+	}
+	for arn, inner := range getters {
+		// searched once, then read from its own cache entry
+		assert.Equal(t, 1, inner.Calls, arn)
+	}
+}
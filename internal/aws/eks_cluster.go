@@ -2,100 +2,264 @@
 package aws
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/mateimicu/kdiscover/internal/cache"
 	"github.com/mateimicu/kdiscover/internal/cluster"
 	log "github.com/sirupsen/logrus"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-const (
-	clientAPIVersion = "client.authentication.k8s.io/v1beta1"
-)
+// ClusterGetter sends all the clusters it finds on the channel and closes
+// it when done or when the context is canceled. The returned error
+// means that not all the clusters were found.
+type ClusterGetter interface {
+	GetClusters(ctx context.Context, ch chan<- *cluster.Cluster) error
+}
 
-var (
-	options = map[AuthType][]string{
-		useAWSCLI:           {"eks", "get-token", "--cluster-name"},
-		useIAMAuthenticator: {"token", "-i"},
-	}
-)
+// Options describes where to search for EKS clusters
+type Options struct {
+	// Regions to search in
+	Regions []string
+	// EnabledRegions drops, for every identity, the regions that are not
+	// enabled for its account, opt-in regions are enabled per account
+	EnabledRegions bool
+	// Profiles are the named profiles to use, if empty the default
+	// credential chain is used
+	Profiles []string
+	// RoleARNs are assumed from every profile, if empty the credentials
+	// of the profile are used directly
+	RoleARNs        []string
+	RoleSessionName string
+	RoleExternalID  string
+	// Organization enables the discovery of the accounts using AWS
+	// Organizations, the profiles need access to the management account
+	Organization bool
+	// OrganizationalUnits restricts the organization accounts to
+	// the ones from this units
+	OrganizationalUnits []string
+	// OrganizationRoleTemplate is used to derive the role ARN that is
+	// assumed in every account of the organization
+	OrganizationRoleTemplate string
+	// RegionTimeout limits the time spent searching a region,
+	// zero means no limit
+	RegionTimeout time.Duration
+	// Concurrency is the number of DescribeCluster calls done in
+	// parallel across all the regions, zero means DefaultConcurrency
+	Concurrency int
+	// Cache stores the clusters of every region, nil disables it
+	Cache *cache.Cache
+	// Refresh ignores the cached clusters but still updates the cache
+	Refresh bool
+	// Auth is the exec plugin written in the kubeconfig for the clusters,
+	// nil picks the authenticator from the version of the AWS CLI
+	Auth *Auth
+}
 
-func getConfigAuthInfo(cls *cluster.Cluster, authType AuthType) *clientcmdapi.AuthInfo {
-	authInfo := clientcmdapi.NewAuthInfo()
-	args := make([]string, len(options[authType]))
-	copy(args, options[authType])
-	args = append(args, cls.Name, "--region", cls.Region)
-
-	authInfo.Exec = &clientcmdapi.ExecConfig{
-		Command:    commands[authType],
-		Args:       args,
-		APIVersion: clientAPIVersion}
-	return authInfo
+func (o Options) profiles() []string {
+	if len(o.Profiles) == 0 {
+		return []string{""}
+	}
+	return o.Profiles
 }
 
-type ClusterGetter interface {
-	GetClusters(ch chan<- *cluster.Cluster)
+func (o Options) identities() []Identity {
+	profiles := o.profiles()
+	roles := o.RoleARNs
+	if len(roles) == 0 {
+		roles = []string{""}
+	}
+
+	identities := make([]Identity, 0, len(profiles)*len(roles))
+	for _, profile := range profiles {
+		for _, role := range roles {
+			identities = append(identities, Identity{
+				Profile:         profile,
+				RoleARN:         role,
+				RoleSessionName: o.RoleSessionName,
+				RoleExternalID:  o.RoleExternalID,
+			})
+		}
+	}
+	return identities
 }
 
-func GetEKSClusters(regions []string) []*cluster.Cluster {
-	clients := make([]ClusterGetter, 0, len(regions))
+// getIdentities returns the identities from the options, querying
+// AWS Organizations if needed. The profiles whose organization accounts
+// can't be listed are returned as failures.
+func getIdentities(ctx context.Context, opts Options) ([]Identity, []error) {
+	if !opts.Organization {
+		return opts.identities(), nil
+	}
+
+	identities := []Identity{}
+	errs := []error{}
+	if len(opts.RoleARNs) != 0 {
+		identities = append(identities, opts.identities()...)
+	}
+	for _, profile := range opts.profiles() {
+		base := Identity{Profile: profile}
+		cfg, err := newConfig(ctx, base, credentialsRegion(opts.Regions))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"identity": base.String(),
+				"error":    err.Error(),
+			}).Error("Failed to load AWS SDK config")
+			errs = append(errs, &Failure{Identity: base, Err: err})
+			continue
+		}
 
-	for _, region := range regions {
+		accounts, err := organizationIdentities(ctx, organizations.NewFromConfig(cfg), base, opts)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"identity": base.String(),
+				"error":    err.Error(),
+			}).Error("Failed to list the organization accounts")
+			errs = append(errs, &Failure{Identity: base, Err: fmt.Errorf("can't list the organization accounts: %w", err)})
+			continue
+		}
 		log.WithFields(log.Fields{
-			"region": region,
-		}).Info("Initialize client")
-		eks, err := NewEKS(region)
+			"identity": base.String(),
+			"accounts": len(accounts),
+		}).Info("Found organization accounts")
+		identities = append(identities, accounts...)
+	}
+	return identities, errs
+}
+
+// GetEKSClusters will query every identity and region combination
+// described by the options. If some identities, regions or clusters fail
+// a *DiscoveryError is returned together with the clusters that were found.
+func GetEKSClusters(ctx context.Context, opts Options) (*Discovery, error) {
+	if len(opts.Regions) == 0 {
+		return &Discovery{Clusters: []*cluster.Cluster{}, Searched: []Scope{}}, nil
+	}
+	identities, identityErrs := getIdentities(ctx, opts)
+	clients := make([]ClusterGetter, 0, len(identities)*len(opts.Regions))
+	scopes := make([]Scope, 0, cap(clients))
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	limiter := NewLimiter(concurrency)
+
+	for _, identity := range identities {
+		// the config is shared between regions so a role is assumed only once
+		cfg, err := newConfig(ctx, identity, credentialsRegion(opts.Regions))
 		if err != nil {
 			log.WithFields(log.Fields{
-				"region": region,
-				"error":  err.Error(),
-			}).Error("Failed to create AWS SDK session")
+				"identity": identity.String(),
+				"error":    err.Error(),
+			}).Error("Failed to load AWS SDK config")
+			identityErrs = append(identityErrs, &Failure{Identity: identity, Err: err})
 			continue
 		}
 
-		clients = append(clients, ClusterGetter(eks))
+		store := opts.Cache
+		caller := ""
+		if store != nil {
+			if caller, err = callerARN(ctx, sts.NewFromConfig(cfg)); err != nil {
+				// the regions fail with the same error, they are not cached
+				log.WithFields(log.Fields{
+					"identity": identity.String(),
+					"error":    err.Error(),
+				}).Warn("Can't identify the credentials, the cache is not used")
+				store = nil
+			}
+		}
+
+		for _, region := range identityRegions(ctx, ec2.NewFromConfig(cfg), identity, opts) {
+			log.WithFields(log.Fields{
+				"region":   region,
+				"identity": identity.String(),
+			}).Info("Initialize client")
+			client := NewEKS(cfg, region, identity)
+			client.Limiter = limiter
+			scopes = append(scopes, Scope{Identity: identity, Region: region})
+			if store != nil {
+				clients = append(clients, ClusterGetter(newCachedGetter(client, store, opts.Refresh, caller)))
+				continue
+			}
+			clients = append(clients, ClusterGetter(client))
+		}
+	}
+	clusters, errs := searchClients(ctx, clients, opts.RegionTimeout)
+	setAuth(clusters, opts.Auth)
+	d := &Discovery{Clusters: clusters, Searched: []Scope{}}
+	for i, err := range errs {
+		if err == nil {
+			d.Searched = append(d.Searched, scopes[i])
+		}
 	}
-	return getEKSClusters(clients)
+	return d, newDiscoveryError(append(identityErrs, errs...))
 }
 
-// GetEKSClusters will query the given regions and return a list of
+// getEKSClusters will query the given regions and return a list of
 // clusters accesable. It will use the default credential chain for AWS
 // in order to figure out the context for the API calls
-func getEKSClusters(clients []ClusterGetter) []*cluster.Cluster {
-	clusters := make([]*cluster.Cluster, 0, len(clients))
-	ch := make(chan *cluster.Cluster)
+func getEKSClusters(
+	ctx context.Context, clients []ClusterGetter, regionTimeout time.Duration,
+) ([]*cluster.Cluster, error) {
+	clusters, errs := searchClients(ctx, clients, regionTimeout)
+	setAuth(clusters, nil)
+	return clusters, newDiscoveryError(errs)
+}
+
+// setAuth adds the EKS specific auth config to the clusters
+func setAuth(clusters []*cluster.Cluster, auth *Auth) {
+	if auth == nil {
+		auth = &Auth{Type: getAuthType()}
+	}
+	for _, c := range clusters {
+		c.GenerateAuthInfo = func(cls *cluster.Cluster) *clientcmdapi.AuthInfo {
+			return getConfigAuthInfo(cls, auth)
+		}
+	}
+}
+
+// searchClients returns the clusters found by all the clients and the
+// error of every client, in the same order as the clients
+func searchClients(
+	ctx context.Context, clients []ClusterGetter, regionTimeout time.Duration,
+) ([]*cluster.Cluster, []error) {
+	// the clusters are kept per client so they are returned in the order
+	// of the clients, whatever region answers first
+	found := make([][]*cluster.Cluster, len(clients))
+	errs := make([]error, len(clients))
 
 	var wg sync.WaitGroup
-	wg.Add(len(clients))
+	wg.Add(2 * len(clients))
 
-	for _, c := range clients {
+	for i, c := range clients {
 		regionCh := make(chan *cluster.Cluster)
-		go c.GetClusters(regionCh)
+		go func(i int, c ClusterGetter) {
+			defer wg.Done()
+			regionCtx, cancel := ctx, context.CancelFunc(func() {})
+			if regionTimeout > 0 {
+				regionCtx, cancel = context.WithTimeout(ctx, regionTimeout)
+			}
+			defer cancel()
+			errs[i] = c.GetClusters(regionCtx, regionCh)
+		}(i, c)
 
-		// fan-in from all the regions to one output channel
-		go func(out chan<- *cluster.Cluster, wg *sync.WaitGroup) {
+		go func(i int) {
+			defer wg.Done()
 			for cls := range regionCh {
-				out <- cls
+				found[i] = append(found[i], cls)
 			}
-			wg.Done()
-		}(ch, &wg)
+		}(i)
 	}
+	wg.Wait()
 
-	// close the channel when all regions have finished the querys
-	go func(wg *sync.WaitGroup, out chan<- *cluster.Cluster) {
-		defer close(out)
-		wg.Wait()
-	}(&wg, ch)
-
-	authType := getAuthType()
-	for c := range ch {
-		// add EKS specific auth config
-		// create a new function in order to cache authType
-		c.GenerateAuthInfo = func(cls *cluster.Cluster) *clientcmdapi.AuthInfo {
-			return getConfigAuthInfo(cls, authType)
-		}
-		clusters = append(clusters, c)
+	clusters := make([]*cluster.Cluster, 0, len(clients))
+	for _, f := range found {
+		clusters = append(clusters, f...)
 	}
-
-	return clusters
+	return clusters, errs
 }
@@ -2,23 +2,38 @@
 package aws
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mateimicu/kdiscover/internal/cluster"
 	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type fakeClusterGetter struct {
 	Region   string
 	Clusters []*cluster.Cluster
+	// Hang blocks the getter until the context is done
+	Hang bool
+	// Delay is waited before sending the clusters
+	Delay time.Duration
 }
 
-func (c *fakeClusterGetter) GetClusters(ch chan<- *cluster.Cluster) {
+func (c *fakeClusterGetter) GetClusters(ctx context.Context, ch chan<- *cluster.Cluster) error {
+	defer close(ch)
+	if c.Hang {
+		<-ctx.Done()
+		return &Failure{Region: c.Region, Err: ctx.Err()}
+	}
+	time.Sleep(c.Delay)
 	for _, cls := range c.Clusters {
 		ch <- cls
 	}
-	close(ch)
+	return nil
 }
 
 func newFakeGetter(count int) *fakeClusterGetter {
@@ -91,8 +106,171 @@ func TestGetEKSClusters(t *testing.T) {
 			}
 			allClusters := getAllClusters(tt.Clients)
 
-			r := getEKSClusters(clients)
+			r, err := getEKSClusters(context.Background(), clients, 0)
+			assert.NoError(t, err)
 			assert.ElementsMatch(t, r, allClusters)
 		})
 	}
 }
+
+func TestGetEKSClustersRegionTimeout(t *testing.T) {
+	t.Parallel()
+	healthy := newFakeGetter(3)
+	healthy.Region = "eu-west-1"
+	slow := newFakeGetter(3)
+	slow.Region = "ap-east-1"
+	slow.Hang = true
+
+	r, err := getEKSClusters(context.Background(), []ClusterGetter{healthy, slow}, 10*time.Millisecond)
+	assert.ElementsMatch(t, healthy.Clusters, r)
+
+	var discoveryErr *DiscoveryError
+	if assert.ErrorAs(t, err, &discoveryErr) {
+		assert.Len(t, discoveryErr.Failures, 1)
+		assert.Equal(t, "ap-east-1", discoveryErr.Failures[0].Region)
+		assert.ErrorIs(t, discoveryErr.Failures[0], context.DeadlineExceeded)
+	}
+}
+
+func TestGetEKSClustersGlobalTimeout(t *testing.T) {
+	t.Parallel()
+	slow := newFakeGetter(1)
+	slow.Hang = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r, err := getEKSClusters(ctx, []ClusterGetter{newFakeGetter(2), slow}, 0)
+	assert.Len(t, r, 2)
+	assert.Error(t, err)
+}
+
+func TestGetConfigAuthInfoProfile(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Profile     string
+		ExpectedEnv []clientcmdapi.ExecEnvVar
+	}{
+		{"", nil},
+		{"dev", []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "dev"}}},
+	}
+
+	for _, tt := range tts {
+		for _, authType := range []AuthType{useAWSCLI, useIAMAuthenticator, useKdiscover} {
+			testname := fmt.Sprintf("profile %q auth type %v", tt.Profile, authType)
+			t.Run(testname, func(t *testing.T) {
+				cls := cluster.GetMockClusters(1)[0]
+				cls.Profile = tt.Profile
+
+				authInfo := getConfigAuthInfo(cls, &Auth{Type: authType})
+				assert.Equal(t, commands[authType], authInfo.Exec.Command)
+				assert.Equal(t, tt.ExpectedEnv, authInfo.Exec.Env)
+			})
+		}
+	}
+}
+
+func TestGetConfigAuthInfoRole(t *testing.T) {
+	t.Parallel()
+	role := "arn:aws:iam::123456789012:role/ReadOnly"
+	tts := []struct {
+		AuthType     AuthType
+		ExpectedArgs []string
+	}{
+		{useAWSCLI, []string{"--role-arn", role}},
+		{useIAMAuthenticator, []string{"-r", role}},
+		{useKdiscover, []string{"--role-arn", role}},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("auth type %v", tt.AuthType)
+		t.Run(testname, func(t *testing.T) {
+			cls := cluster.GetMockClusters(1)[0]
+			cls.RoleARN = role
+
+			authInfo := getConfigAuthInfo(cls, &Auth{Type: tt.AuthType})
+			args := authInfo.Exec.Args
+			assert.Equal(t, tt.ExpectedArgs, args[len(args)-2:])
+		})
+	}
+}
+
+func TestOptionsIdentities(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Options  Options
+		Expected []Identity
+	}{
+		{Options{}, []Identity{{}}},
+		{
+			Options{Profiles: []string{"dev", "prod"}},
+			[]Identity{{Profile: "dev"}, {Profile: "prod"}},
+		},
+		{
+			Options{RoleARNs: []string{"role-a", "role-b"}, RoleSessionName: "s", RoleExternalID: "e"},
+			[]Identity{
+				{RoleARN: "role-a", RoleSessionName: "s", RoleExternalID: "e"},
+				{RoleARN: "role-b", RoleSessionName: "s", RoleExternalID: "e"},
+			},
+		},
+		{
+			Options{Profiles: []string{"hub"}, RoleARNs: []string{"role-a", "role-b"}},
+			[]Identity{{Profile: "hub", RoleARN: "role-a"}, {Profile: "hub", RoleARN: "role-b"}},
+		},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%+v", tt.Options)
+		t.Run(testname, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, tt.Options.identities())
+		})
+	}
+}
+
+func TestSearchClientsErrors(t *testing.T) {
+	t.Parallel()
+	slow := newFakeGetter(1)
+	slow.Hang = true
+
+	clients := []ClusterGetter{newFakeGetter(2), slow, newFakeGetter(1)}
+	r, errs := searchClients(context.Background(), clients, 10*time.Millisecond)
+	assert.Len(t, r, 3)
+	if assert.Len(t, errs, 3) {
+		assert.NoError(t, errs[0])
+		assert.Error(t, errs[1])
+		assert.NoError(t, errs[2])
+	}
+}
+
+func TestSearchClientsOrder(t *testing.T) {
+	t.Parallel()
+	slow, fast := newFakeGetter(2), newFakeGetter(1)
+	slow.Delay = 20 * time.Millisecond
+
+	r, _ := searchClients(context.Background(), []ClusterGetter{slow, fast}, 0)
+	// the clusters follow the order of the clients, not of the answers
+	assert.Equal(t, append(append([]*cluster.Cluster{}, slow.Clusters...), fast.Clusters...), r)
+}
+
+func TestGetEKSClustersIdentityFailure(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+
+	for _, organization := range []bool{false, true} {
+		t.Run(fmt.Sprintf("organization %v", organization), func(t *testing.T) {
+			opts := Options{Regions: []string{"eu-west-1"}, Profiles: []string{"missing"}, Organization: organization}
+			d, err := GetEKSClusters(context.Background(), opts)
+			assert.Empty(t, d.Clusters)
+			// nothing was searched so nothing can be pruned
+			assert.Empty(t, d.Searched)
+
+			var discoveryErr *DiscoveryError
+			if assert.ErrorAs(t, err, &discoveryErr) && assert.Len(t, discoveryErr.Failures, 1) {
+				failure := discoveryErr.Failures[0]
+				assert.Equal(t, Identity{Profile: "missing"}, failure.Identity)
+				assert.Empty(t, failure.Region)
+				assert.True(t, strings.HasPrefix(failure.Error(), "profile missing: "), failure.Error())
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@
 package aws
 
 import (
+	"fmt"
 	"os/exec"
 	"regexp"
+	"strings"
 
 	"github.com/Masterminds/semver"
+	"github.com/mateimicu/kdiscover/internal/cluster"
 	log "github.com/sirupsen/logrus"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type AuthType int
@@ -14,22 +18,233 @@ type AuthType int
 const (
 	useAWSCLI AuthType = iota
 	useIAMAuthenticator
+	useKubelogin
+	useKdiscover
+	useCustom
 )
 
 const (
 	commandAWScli           = "aws"
 	commandIAMAuthenticator = "aws-iam-authenticator"
+	commandKubelogin        = "kubectl"
+	commandKdiscover        = "kdiscover"
+	commandKdiscoverPlugin  = "kubectl-discover"
+)
+
+const (
+	clientAPIVersion   = "client.authentication.k8s.io/v1beta1"
+	clientAPIVersionV1 = "client.authentication.k8s.io/v1"
+	profileEnvVar      = "AWS_PROFILE"
+	regionEnvVar       = "AWS_REGION"
 )
 
 var (
+	authTypeNames = map[AuthType]string{
+		useAWSCLI:           "aws-cli",
+		useIAMAuthenticator: "iam-authenticator",
+		useKubelogin:        "kubelogin",
+		useKdiscover:        "kdiscover",
+		useCustom:           "custom",
+	}
+
+	// commands are the AWS authenticators, the entries using them are
+	// recognized by prune
 	commands = map[AuthType]string{
 		useAWSCLI:           commandAWScli,
 		useIAMAuthenticator: commandIAMAuthenticator,
+		useKdiscover:        commandKdiscover,
+	}
+	options = map[AuthType][]string{
+		useAWSCLI:           {"eks", "get-token", "--cluster-name"},
+		useIAMAuthenticator: {"token", "-i"},
+		useKubelogin:        {"oidc-login", "get-token"},
+		useKdiscover:        {"token", "--cluster"},
+	}
+	roleOptions = map[AuthType]string{
+		useAWSCLI:           "--role-arn",
+		useIAMAuthenticator: "-r",
+		useKdiscover:        "--role-arn",
+	}
+
+	apiVersions = map[string]string{
+		"v1beta1": clientAPIVersion,
+		"v1":      clientAPIVersionV1,
 	}
 
 	awsCLIVersionCommand = []string{"aws", "--version"}
 )
 
+func (a AuthType) String() string {
+	return authTypeNames[a]
+}
+
+// IsKdiscover tells if the users run the token command of kdiscover
+func (a AuthType) IsKdiscover() bool {
+	return a == useKdiscover
+}
+
+// AuthTypeNames returns the names accepted by ParseAuthType
+func AuthTypeNames() []string {
+	names := make([]string, 0, len(authTypeNames))
+	for a := useAWSCLI; a <= useCustom; a++ {
+		names = append(names, a.String())
+	}
+	return names
+}
+
+// ParseAuthType returns the authenticator with the name, an empty name
+// picks the AWS CLI if it can generate tokens, otherwise
+// aws-iam-authenticator
+func ParseAuthType(name string) (AuthType, error) {
+	if name == "" {
+		return getAuthType(), nil
+	}
+	for a, n := range authTypeNames {
+		if n == name {
+			return a, nil
+		}
+	}
+	return useAWSCLI, fmt.Errorf("unknown authenticator %q, use one of %v", name, strings.Join(AuthTypeNames(), ", "))
+}
+
+// ParseAPIVersion returns the client.authentication.k8s.io version, the
+// short names v1beta1 and v1 are accepted
+func ParseAPIVersion(version string) (string, error) {
+	if full, ok := apiVersions[version]; ok {
+		return full, nil
+	}
+	for _, full := range apiVersions {
+		if full == version {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("unknown exec API version %q, use v1beta1 or v1", version)
+}
+
+// EnvTemplate is an environment variable of the exec plugin with the
+// value rendered for every cluster
+type EnvTemplate struct {
+	Name  string
+	Value *cluster.NameTemplate
+}
+
+// Auth describes the exec plugin of the users written in the kubeconfig
+type Auth struct {
+	Type AuthType
+	// Command replaces the command of the authenticator, it is required
+	// by the custom authenticator
+	Command *cluster.NameTemplate
+	// Args are appended to the arguments of the authenticator
+	Args []*cluster.NameTemplate
+	// Env is added to the environment of the command, after AWS_PROFILE
+	// which is set for the clusters found with a profile
+	Env []EnvTemplate
+	// APIVersion defaults to client.authentication.k8s.io/v1beta1
+	APIVersion string
+	// InteractiveMode defaults to IfAvailable for the v1 API version,
+	// which requires it
+	InteractiveMode    clientcmdapi.ExecInteractiveMode
+	ProvideClusterInfo bool
+}
+
+// Validate checks that the authenticator has everything it needs
+func (a *Auth) Validate() error {
+	switch {
+	case a.Type == useCustom && a.Command == nil:
+		return fmt.Errorf("the %v authenticator needs a command", a.Type)
+	case a.Type == useKubelogin && len(a.Args) == 0:
+		return fmt.Errorf("the %v authenticator needs arguments, at least --oidc-issuer-url and --oidc-client-id", a.Type)
+	}
+	switch a.InteractiveMode {
+	case "", clientcmdapi.NeverExecInteractiveMode, clientcmdapi.IfAvailableExecInteractiveMode,
+		clientcmdapi.AlwaysExecInteractiveMode:
+	default:
+		return fmt.Errorf("unknown interactive mode %q, use %v, %v or %v", a.InteractiveMode,
+			clientcmdapi.NeverExecInteractiveMode, clientcmdapi.IfAvailableExecInteractiveMode,
+			clientcmdapi.AlwaysExecInteractiveMode)
+	}
+	return nil
+}
+
+// ClusterSpecific reports whether the arguments of the authenticator
+// always include the cluster name and region, so the users of two
+// clusters never have the same exec configuration
+func (a *Auth) ClusterSpecific() bool {
+	_, ok := commands[a.Type]
+	return ok
+}
+
+// render executes a template of the authenticator, the templates were
+// checked when parsed so a failure only drops the value
+func render(t *cluster.NameTemplate, cls *cluster.Cluster) string {
+	value, err := t.Execute(cls)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"cluster": cls.Name,
+			"error":   err,
+		}).Warn("Can't render the authenticator template")
+	}
+	return value
+}
+
+func setEnv(env []clientcmdapi.ExecEnvVar, name, value string) []clientcmdapi.ExecEnvVar {
+	for i := range env {
+		if env[i].Name == name {
+			env[i].Value = value
+			return env
+		}
+	}
+	return append(env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+}
+
+func getConfigAuthInfo(cls *cluster.Cluster, auth *Auth) *clientcmdapi.AuthInfo {
+	authInfo := clientcmdapi.NewAuthInfo()
+	args := make([]string, len(options[auth.Type]))
+	copy(args, options[auth.Type])
+	if _, ok := commands[auth.Type]; ok {
+		args = append(args, cls.Name, "--region", cls.Region)
+		if cls.RoleARN != "" {
+			args = append(args, roleOptions[auth.Type], cls.RoleARN)
+		}
+	}
+	for _, arg := range auth.Args {
+		args = append(args, render(arg, cls))
+	}
+
+	command := commands[auth.Type]
+	if auth.Type == useKubelogin {
+		command = commandKubelogin
+	}
+	if auth.Command != nil {
+		command = render(auth.Command, cls)
+	}
+	apiVersion := auth.APIVersion
+	if apiVersion == "" {
+		apiVersion = clientAPIVersion
+	}
+	interactiveMode := auth.InteractiveMode
+	if interactiveMode == "" && apiVersion == clientAPIVersionV1 {
+		interactiveMode = clientcmdapi.IfAvailableExecInteractiveMode
+	}
+
+	authInfo.Exec = &clientcmdapi.ExecConfig{
+		Command:            command,
+		Args:               args,
+		APIVersion:         apiVersion,
+		InteractiveMode:    interactiveMode,
+		ProvideClusterInfo: auth.ProvideClusterInfo,
+	}
+
+	// pin the profile so the context does not depend on the shell environment
+	if cls.Profile != "" {
+		authInfo.Exec.Env = setEnv(authInfo.Exec.Env, profileEnvVar, cls.Profile)
+	}
+	for _, env := range auth.Env {
+		authInfo.Exec.Env = setEnv(authInfo.Exec.Env, env.Name, render(env.Value, cls))
+	}
+	return authInfo
+}
+
 func getAuthType() AuthType {
 	// According to the docs the first version that supports this is 1.18.17
 	// See: https://docs.aws.amazon.com/eks/latest/userguide/create-kubeconfig.html
@@ -3,9 +3,13 @@ package aws
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/Masterminds/semver"
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 func TestAWSCLIVersion(t *testing.T) {
@@ -38,3 +42,107 @@ func TestAWSCLIVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAuthType(t *testing.T) {
+	t.Parallel()
+	for _, name := range AuthTypeNames() {
+		a, err := ParseAuthType(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, a.String())
+	}
+	_, err := ParseAuthType("gcloud")
+	assert.Error(t, err)
+}
+
+func TestParseAPIVersion(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Version, Expected string
+	}{
+		{"v1", "client.authentication.k8s.io/v1"},
+		{"v1beta1", "client.authentication.k8s.io/v1beta1"},
+		{"client.authentication.k8s.io/v1", "client.authentication.k8s.io/v1"},
+		{"v1alpha1", ""},
+	}
+	for _, tt := range tts {
+		v, err := ParseAPIVersion(tt.Version)
+		assert.Equal(t, tt.Expected, v)
+		assert.Equal(t, tt.Expected == "", err != nil)
+	}
+}
+
+func TestAuthValidate(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Auth  Auth
+		Valid bool
+	}{
+		{Auth{Type: useAWSCLI}, true},
+		{Auth{Type: useCustom}, false},
+		{Auth{Type: useCustom, Command: mustTemplate(t, "login")}, true},
+		{Auth{Type: useKubelogin}, false},
+		{Auth{Type: useKubelogin, Args: []*cluster.NameTemplate{mustTemplate(t, "--oidc-client-id=kubernetes")}}, true},
+		{Auth{Type: useAWSCLI, InteractiveMode: "Sometimes"}, false},
+	}
+	for _, tt := range tts {
+		assert.Equal(t, tt.Valid, tt.Auth.Validate() == nil, "%+v", tt.Auth)
+	}
+}
+
+func TestAuthClusterSpecific(t *testing.T) {
+	t.Parallel()
+	for _, name := range AuthTypeNames() {
+		a, err := ParseAuthType(name)
+		assert.NoError(t, err)
+		auth := &Auth{Type: a}
+		other := cluster.GetMockClusters(1)[0]
+		other.Name = "other"
+		specific := !reflect.DeepEqual(
+			getConfigAuthInfo(cluster.GetMockClusters(1)[0], auth).Exec.Args, getConfigAuthInfo(other, auth).Exec.Args)
+		assert.Equal(t, specific, auth.ClusterSpecific(), name)
+	}
+}
+
+func TestGetConfigAuthInfoCustom(t *testing.T) {
+	t.Parallel()
+	cls := cluster.GetMockClusters(1)[0]
+	cls.Profile = "dev"
+	auth := &Auth{
+		Type:    useCustom,
+		Command: mustTemplate(t, "login-{{.Provider}}"),
+		Args:    []*cluster.NameTemplate{mustTemplate(t, "--cluster={{.Name}}"), mustTemplate(t, `{{tag "missing"}}`)},
+		Env: []EnvTemplate{
+			{Name: "AWS_REGION", Value: mustTemplate(t, "{{.Region}}")},
+			{Name: "AWS_PROFILE", Value: mustTemplate(t, "other")},
+		},
+		APIVersion:         clientAPIVersionV1,
+		ProvideClusterInfo: true,
+	}
+
+	exec := getConfigAuthInfo(cls, auth).Exec
+	assert.Equal(t, "login-"+cls.Provider.String(), exec.Command)
+	assert.Equal(t, []string{"--cluster=" + cls.Name, ""}, exec.Args)
+	assert.Equal(t, []clientcmdapi.ExecEnvVar{
+		{Name: "AWS_PROFILE", Value: "other"},
+		{Name: "AWS_REGION", Value: cls.Region},
+	}, exec.Env)
+	assert.Equal(t, clientAPIVersionV1, exec.APIVersion)
+	assert.Equal(t, clientcmdapi.IfAvailableExecInteractiveMode, exec.InteractiveMode)
+	assert.True(t, exec.ProvideClusterInfo)
+}
+
+func TestGetConfigAuthInfoKubelogin(t *testing.T) {
+	t.Parallel()
+	cls := cluster.GetMockClusters(1)[0]
+	auth := &Auth{
+		Type:            useKubelogin,
+		Args:            []*cluster.NameTemplate{mustTemplate(t, "--oidc-issuer-url=https://{{.AccountID}}")},
+		InteractiveMode: clientcmdapi.AlwaysExecInteractiveMode,
+	}
+
+	exec := getConfigAuthInfo(cls, auth).Exec
+	assert.Equal(t, "kubectl", exec.Command)
+	assert.Equal(t, []string{"oidc-login", "get-token", "--oidc-issuer-url=https://" + cls.AccountID}, exec.Args)
+	assert.Equal(t, clientAPIVersion, exec.APIVersion)
+	assert.Equal(t, clientcmdapi.AlwaysExecInteractiveMode, exec.InteractiveMode)
+}
@@ -2,20 +2,26 @@
 package aws
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/aws/aws-sdk-go/service/eks" //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
-	"github.com/aws/aws-sdk-go/service/eks/eksiface"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
 	"github.com/mateimicu/kdiscover/internal/cluster"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockEKSClient struct {
-	eksiface.EKSAPI
 	// The clusters we need to return
 	Clusters []*cluster.Cluster
 
@@ -26,7 +32,7 @@ type mockEKSClient struct {
 	// that the first call and the fourth one will fail
 	ErrorOnDescribe map[int]error
 
-	// ListClustersPages will fail in the calls specified here (0, 3) means
+	// ListClusters will fail in the calls specified here (0, 3) means
 	// that the first call and the fourth one will fail
 	ErrorOnList map[int]error
 
@@ -34,69 +40,74 @@ type mockEKSClient struct {
 	DescribeCallCount int
 }
 
-// TODO(mmicu): implement DescribeCluster and ListClustersPages
-
-func (c *mockEKSClient) ListClustersPages(_ *eks.ListClustersInput, fn func(*eks.ListClustersOutput, bool) bool) error {
-	if err, ok := c.ErrorOnList[c.ListCallCount]; ok {
+func (c *mockEKSClient) ListClusters(
+	ctx context.Context, input *eks.ListClustersInput, _ ...func(*eks.Options),
+) (*eks.ListClustersOutput, error) {
+	defer func() {
 		c.ListCallCount++
-		return err
+	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := c.ErrorOnList[c.ListCallCount]; ok {
+		return nil, err
 	}
-	start := 0
-	end := min(c.PageSize, len(c.Clusters))
-	for end <= len(c.Clusters) {
-		o := eks.ListClustersOutput{}
-		clusters := []*string{}
-
-		// prepare clusters
-		for _, cls := range c.Clusters[start:end] {
-			localCluster := *cls
-			clusters = append(clusters, &localCluster.Name)
-		}
-		o.Clusters = clusters
-		lastPage := end == len(c.Clusters)
-		fn(&o, lastPage)
-
-		if lastPage {
-			break
-		}
 
-		step := min(c.PageSize, len(c.Clusters)-end)
-		start += c.PageSize
-		end += step
+	// the token is the index of the first cluster from the page
+	start := 0
+	if input.NextToken != nil {
+		start, _ = strconv.Atoi(*input.NextToken)
 	}
+	end := min(start+c.PageSize, len(c.Clusters))
 
-	return nil
+	o := eks.ListClustersOutput{Clusters: []string{}}
+	for _, cls := range c.Clusters[start:end] {
+		o.Clusters = append(o.Clusters, cls.Name)
+	}
+	if end < len(c.Clusters) {
+		o.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return &o, nil
 }
 
-func (c *mockEKSClient) DescribeCluster(input *eks.DescribeClusterInput) (*eks.DescribeClusterOutput, error) {
+func (c *mockEKSClient) DescribeCluster(
+	ctx context.Context, input *eks.DescribeClusterInput, _ ...func(*eks.Options),
+) (*eks.DescribeClusterOutput, error) {
 	defer func() {
 		c.DescribeCallCount++
 	}()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if err, ok := c.ErrorOnDescribe[c.DescribeCallCount]; ok {
 		return nil, err
 	}
 
 	for _, cls := range c.Clusters {
-		localCluster := *cls
 		if *input.Name == cls.Name {
-			cluster := eks.Cluster{}
-			cluster.Arn = &localCluster.ID
-			cluster.Endpoint = &localCluster.Endpoint
-			cluster.Name = &localCluster.Name
-			cluster.Status = &localCluster.Status
-
-			cert := eks.Certificate{}
 			data := base64.StdEncoding.EncodeToString([]byte(cls.CertificateAuthorityData))
-			cert.Data = &data
-			cluster.CertificateAuthority = &cert
+			cluster := types.Cluster{
+				Arn:                  aws.String(cls.ID),
+				Endpoint:             aws.String(cls.Endpoint),
+				Name:                 aws.String(cls.Name),
+				Status:               types.ClusterStatus(cls.Status),
+				CertificateAuthority: &types.Certificate{Data: aws.String(data)},
+				Version:              aws.String(cls.KubernetesVersion),
+				PlatformVersion:      aws.String(cls.PlatformVersion),
+				Tags:                 cls.Tags,
+				CreatedAt:            aws.Time(cls.CreatedAt),
+				ResourcesVpcConfig: &types.VpcConfigResponse{
+					EndpointPublicAccess:  cls.EndpointPublicAccess,
+					EndpointPrivateAccess: cls.EndpointPrivateAccess,
+					VpcId:                 aws.String(cls.VpcID),
+				},
+				Identity: &types.Identity{Oidc: &types.OIDC{Issuer: aws.String(cls.OIDCIssuer)}},
+			}
 
-			// TODO(mmicu): populate cluster data here
-			out := eks.DescribeClusterOutput{}
-			out.Cluster = &cluster
-			return &out, nil
+			return &eks.DescribeClusterOutput{Cluster: &cluster}, nil
 		}
 	}
-	return nil, fmt.Errorf("can't find cluster %v", input.Name)
+	return nil, fmt.Errorf("can't find cluster %v", *input.Name)
 }
 
 type testCase struct {
@@ -260,7 +271,7 @@ func TestGetClustersNoFailure(t *testing.T) {
 				EKS:    &client,
 				Region: tt.Region,
 			}
-			go c.GetClusters(ch)
+			go c.GetClusters(context.Background(), ch)
 			clusters := []*cluster.Cluster{}
 			for c := range ch {
 				clusters = append(clusters, c)
@@ -268,9 +279,10 @@ func TestGetClustersNoFailure(t *testing.T) {
 
 			assert.Equal(t, len(tt.Client.Clusters)-describeErrorCount, len(clusters))
 
-			// fix Regions
+			// fix Regions and providers
 			for _, c := range tt.Client.Clusters {
 				c.Region = tt.Region
+				c.Provider = cluster.AWS
 			}
 
 			// nillify function fields
@@ -282,6 +294,8 @@ func TestGetClustersNoFailure(t *testing.T) {
 			for _, c := range clusters {
 				c.GenerateClusterConfig = nil
 				c.GenerateAuthInfo = nil
+				assert.False(t, c.DiscoveredAt.IsZero())
+				c.DiscoveredAt = time.Time{}
 			}
 
 			if describeErrorCount == 0 {
@@ -334,7 +348,7 @@ func TestGetClustersListFailure(t *testing.T) {
 				EKS:    &client,
 				Region: tt.Region,
 			}
-			go c.GetClusters(ch)
+			go c.GetClusters(context.Background(), ch)
 			clusters := []*cluster.Cluster{}
 			for c := range ch {
 				clusters = append(clusters, c)
@@ -349,3 +363,201 @@ func TestGetClustersListFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountFromARN(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		ARN, Expected string
+	}{
+		{"arn:aws:eks:eu-west-1:123456789012:cluster/prod", "123456789012"},
+		{"arn:aws-cn:eks:cn-north-1:123456789012:cluster/prod", "123456789012"},
+		{"clucster-id-0", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%q -> %q", tt.ARN, tt.Expected)
+		t.Run(testname, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, accountFromARN(tt.ARN))
+		})
+	}
+}
+
+func TestGetClustersCanceled(t *testing.T) {
+	t.Parallel()
+	log.SetOutput(io.Discard)
+
+	client := mockEKSClient{
+		Clusters:        cluster.GetMockClusters(10),
+		PageSize:        3,
+		ErrorOnDescribe: map[int]error{},
+		ErrorOnList:     map[int]error{},
+	}
+	c := EKSClient{
+		EKS:    &client,
+		Region: "fakeRegion",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ch := make(chan *cluster.Cluster)
+	go c.GetClusters(ctx, ch)
+	clusters := []*cluster.Cluster{}
+	for c := range ch {
+		clusters = append(clusters, c)
+	}
+
+	assert.Empty(t, clusters)
+	assert.Equal(t, 1, client.ListCallCount)
+	assert.Equal(t, 0, client.DescribeCallCount)
+}
+
+func TestGetClustersListError(t *testing.T) {
+	t.Parallel()
+	log.SetOutput(io.Discard)
+
+	client := mockEKSClient{
+		Clusters:        cluster.GetMockClusters(3),
+		PageSize:        1,
+		ErrorOnDescribe: map[int]error{},
+		ErrorOnList:     map[int]error{1: errors.New("throttled")},
+	}
+	c := EKSClient{
+		EKS:    &client,
+		Region: "fakeRegion",
+	}
+
+	ch := make(chan *cluster.Cluster)
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.GetClusters(context.Background(), ch) }()
+	clusters := []*cluster.Cluster{}
+	for c := range ch {
+		clusters = append(clusters, c)
+	}
+
+	var failure *Failure
+	assert.ErrorAs(t, <-errCh, &failure)
+	assert.Equal(t, "fakeRegion", failure.Region)
+	assert.Empty(t, failure.Cluster)
+	assert.Len(t, clusters, 1)
+}
+
+func TestGetClustersDescribeError(t *testing.T) {
+	t.Parallel()
+	log.SetOutput(io.Discard)
+
+	client := mockEKSClient{
+		Clusters:        cluster.GetMockClusters(3),
+		PageSize:        3,
+		ErrorOnDescribe: map[int]error{1: &smithy.GenericAPIError{Code: "AccessDeniedException"}},
+		ErrorOnList:     map[int]error{},
+	}
+	c := EKSClient{
+		EKS:    &client,
+		Region: "fakeRegion",
+	}
+
+	ch := make(chan *cluster.Cluster)
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.GetClusters(context.Background(), ch) }()
+	clusters := []*cluster.Cluster{}
+	for c := range ch {
+		clusters = append(clusters, c)
+	}
+
+	var discoveryErr *DiscoveryError
+	if assert.ErrorAs(t, <-errCh, &discoveryErr) {
+		assert.Len(t, discoveryErr.Failures, 1)
+		assert.Equal(t, client.Clusters[1].Name, discoveryErr.Failures[0].Cluster)
+		assert.Equal(t, CauseAccessDenied, discoveryErr.Failures[0].Cause())
+	}
+	assert.Len(t, clusters, 2)
+}
+
+// lockedEKSClient serializes the calls to the mock, the clusters
+// are described in parallel
+type lockedEKSClient struct {
+	mu     sync.Mutex
+	client *mockEKSClient
+}
+
+func (c *lockedEKSClient) ListClusters(
+	ctx context.Context, input *eks.ListClustersInput, optFns ...func(*eks.Options),
+) (*eks.ListClustersOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client.ListClusters(ctx, input, optFns...)
+}
+
+func (c *lockedEKSClient) DescribeCluster(
+	ctx context.Context, input *eks.DescribeClusterInput, optFns ...func(*eks.Options),
+) (*eks.DescribeClusterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client.DescribeCluster(ctx, input, optFns...)
+}
+
+func TestGetClustersConcurrent(t *testing.T) {
+	t.Parallel()
+	log.SetOutput(io.Discard)
+
+	client := mockEKSClient{
+		Clusters:        cluster.GetMockClusters(50),
+		PageSize:        7,
+		ErrorOnDescribe: map[int]error{},
+		ErrorOnList:     map[int]error{},
+	}
+	c := EKSClient{
+		EKS:     &lockedEKSClient{client: &client},
+		Region:  "fakeRegion",
+		Limiter: NewLimiter(5),
+	}
+
+	ch := make(chan *cluster.Cluster)
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.GetClusters(context.Background(), ch) }()
+	names := []string{}
+	for cls := range ch {
+		names = append(names, cls.Name)
+	}
+
+	assert.NoError(t, <-errCh)
+	expected := []string{}
+	for _, cls := range client.Clusters {
+		expected = append(expected, cls.Name)
+	}
+	assert.ElementsMatch(t, expected, names)
+	assert.Empty(t, c.Limiter)
+}
+
+func TestGetClustersThrottled(t *testing.T) {
+	t.Parallel()
+	log.SetOutput(io.Discard)
+
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	client := mockEKSClient{
+		Clusters:        cluster.GetMockClusters(2),
+		PageSize:        2,
+		ErrorOnDescribe: map[int]error{0: throttled, 1: throttled, 3: throttled},
+		ErrorOnList:     map[int]error{0: throttled},
+	}
+	c := EKSClient{
+		EKS:     &client,
+		Region:  "fakeRegion",
+		Backoff: Backoff{Base: time.Millisecond, Max: time.Millisecond, MaxAttempts: 3},
+	}
+
+	ch := make(chan *cluster.Cluster)
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.GetClusters(context.Background(), ch) }()
+	clusters := []*cluster.Cluster{}
+	for cls := range ch {
+		clusters = append(clusters, cls)
+	}
+
+	assert.NoError(t, <-errCh)
+	assert.Len(t, clusters, 2)
+	assert.Equal(t, 2, client.ListCallCount)
+	assert.Equal(t, 5, client.DescribeCallCount)
+}
@@ -0,0 +1,142 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Cause is the kind of problem behind a discovery failure
+type Cause string
+
+const (
+	CauseAccessDenied Cause = "AccessDenied"
+	CauseThrottling   Cause = "Throttling"
+	CauseNetwork      Cause = "Network"
+	CauseTimeout      Cause = "Timeout"
+	CauseCanceled     Cause = "Canceled"
+	CauseUnknown      Cause = "Unknown"
+)
+
+var accessDeniedCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+}
+
+// ClassifyError figures out the cause of an error returned by the AWS SDK
+func ClassifyError(err error) Cause {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return CauseTimeout
+	case errors.Is(err, context.Canceled):
+		return CauseCanceled
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if accessDeniedCodes[apiErr.ErrorCode()] {
+			return CauseAccessDenied
+		}
+		if _, ok := retry.DefaultThrottleErrorCodes[apiErr.ErrorCode()]; ok {
+			return CauseThrottling
+		}
+	}
+
+	var sendErr *smithyhttp.RequestSendError
+	var netErr net.Error
+	if errors.As(err, &sendErr) || errors.As(err, &netErr) {
+		return CauseNetwork
+	}
+	return CauseUnknown
+}
+
+// Failure describes a region that could not be listed or, if Cluster
+// is set, a cluster that could not be described. Without a Region the
+// identity itself failed, none of its regions were searched.
+type Failure struct {
+	Region   string
+	Identity Identity
+	Cluster  string
+	Err      error
+}
+
+// Cause classifies the underlying error
+func (f *Failure) Cause() Cause {
+	return ClassifyError(f.Err)
+}
+
+func (f *Failure) Error() string {
+	target := fmt.Sprintf("region %v (%v)", f.Region, f.Identity)
+	if f.Region == "" {
+		target = f.Identity.String()
+	}
+	if f.Cluster != "" {
+		target = fmt.Sprintf("cluster %v in %v", f.Cluster, target)
+	}
+	if f.Cause() == CauseTimeout {
+		return fmt.Sprintf("%v: timed out", target)
+	}
+	return fmt.Sprintf("%v: %v", target, f.Err)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// DiscoveryError is returned when some regions or clusters could not be
+// searched, the clusters found in the other regions are still returned
+type DiscoveryError struct {
+	Failures []*Failure
+}
+
+func (e *DiscoveryError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("%v failures: %v", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+func (e *DiscoveryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// newDiscoveryError aggregates the errors returned by the cluster getters,
+// nil errors are ignored
+func newDiscoveryError(errs []error) error {
+	failures := []*Failure{}
+	for _, err := range errs {
+		var discoveryErr *DiscoveryError
+		var failure *Failure
+		switch {
+		case err == nil:
+			continue
+		case errors.As(err, &discoveryErr):
+			failures = append(failures, discoveryErr.Failures...)
+		case errors.As(err, &failure):
+			failures = append(failures, failure)
+		default:
+			failures = append(failures, &Failure{Err: err})
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return &DiscoveryError{Failures: failures}
+}
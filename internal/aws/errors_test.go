@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Err      error
+		Expected Cause
+	}{
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, CauseAccessDenied},
+		{&smithy.GenericAPIError{Code: "UnrecognizedClientException"}, CauseAccessDenied},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, CauseThrottling},
+		{&smithy.GenericAPIError{Code: "TooManyRequestsException"}, CauseThrottling},
+		{&smithy.GenericAPIError{Code: "ResourceNotFoundException"}, CauseUnknown},
+		{&smithyhttp.RequestSendError{Err: errors.New("connection reset")}, CauseNetwork},
+		{&net.DNSError{Err: "no such host", Name: "eks.mars-1.amazonaws.com"}, CauseNetwork},
+		{fmt.Errorf("list: %w", context.DeadlineExceeded), CauseTimeout},
+		{context.Canceled, CauseCanceled},
+		{errors.New("boom"), CauseUnknown},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%v is %v", tt.Err, tt.Expected)
+		t.Run(testname, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, ClassifyError(tt.Err))
+		})
+	}
+}
+
+func TestNewDiscoveryError(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, newDiscoveryError([]error{nil, nil}))
+
+	err := newDiscoveryError([]error{
+		nil,
+		&Failure{Region: "eu-west-1", Err: context.DeadlineExceeded},
+		&DiscoveryError{Failures: []*Failure{
+			{Region: "us-east-1", Cluster: "a", Err: errors.New("boom")},
+			{Region: "us-east-1", Cluster: "b", Err: errors.New("boom")},
+		}},
+		errors.New("unexpected"),
+	})
+
+	var discoveryErr *DiscoveryError
+	if assert.ErrorAs(t, err, &discoveryErr) {
+		assert.Len(t, discoveryErr.Failures, 4)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
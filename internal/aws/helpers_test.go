@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/require"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newEKSCluster returns a cluster of the account 123456789012 found with
+// the identity and authenticated with the AWS CLI
+func newEKSCluster(name, region string, identity Identity) *cluster.Cluster {
+	cls := cluster.NewCluster()
+	cls.Provider = cluster.AWS
+	cls.Name = name
+	cls.Region = region
+	cls.ID = fmt.Sprintf("arn:aws:eks:%v:123456789012:cluster/%v", region, name)
+	cls.Endpoint = "https://" + name
+	cls.Profile = identity.Profile
+	cls.RoleARN = identity.RoleARN
+	cls.GenerateAuthInfo = func(cls *cluster.Cluster) *clientcmdapi.AuthInfo {
+		return getConfigAuthInfo(cls, &Auth{Type: useAWSCLI})
+	}
+	return cls
+}
+
+func mustTemplate(t *testing.T, value string) *cluster.NameTemplate {
+	t.Helper()
+	tmpl, err := cluster.ParseNameTemplate(value, nil)
+	require.NoError(t, err)
+	return tmpl
+}
@@ -0,0 +1,195 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// OrganizationsAPI is the subset of the AWS Organizations API used
+// to enumerate the accounts
+type OrganizationsAPI interface {
+	DescribeOrganization(context.Context, *organizations.DescribeOrganizationInput,
+		...func(*organizations.Options)) (*organizations.DescribeOrganizationOutput, error)
+	organizations.ListAccountsAPIClient
+	organizations.ListAccountsForParentAPIClient
+	organizations.ListOrganizationalUnitsForParentAPIClient
+}
+
+// Account is an active account of an AWS Organization
+type Account struct {
+	AccountID   string
+	AccountName string
+	// Partition is the partition of the organization, like aws or aws-cn
+	Partition string
+}
+
+// ParseRoleTemplate parses the template used to derive the role ARN
+// for every account of the organization. The template has access
+// to the Account type, a role name is the role with this name in
+// every account.
+func ParseRoleTemplate(value string) (*template.Template, error) {
+	if value == "" {
+		return nil, fmt.Errorf("the organization role is required, for example ReadOnly or " +
+			"'arn:{{.Partition}}:iam::{{.AccountID}}:role/ReadOnly'")
+	}
+	if !strings.HasPrefix(value, "arn:") {
+		value = "arn:{{.Partition}}:iam::{{.AccountID}}:role/" + value
+	}
+	tmpl, err := template.New("organization-role").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// render a sample account in order to catch invalid fields early
+	sample := Account{AccountID: "123456789012", AccountName: "sample", Partition: "aws"}
+	if _, err := renderRoleARN(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func renderRoleARN(tmpl *template.Template, account Account) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, account); err != nil {
+		return "", err
+	}
+	role := buf.String()
+	if err := ValidateRoleARN(role); err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// listAccounts returns the active accounts of the organization. If
+// organizational units are given only the accounts from them
+// (and their children) are returned.
+func listAccounts(ctx context.Context, api OrganizationsAPI, ous []string) ([]Account, error) {
+	accounts := []Account{}
+	collect := func(page []types.Account) {
+		for _, a := range page {
+			if a.State != types.AccountStateActive {
+				log.WithFields(log.Fields{
+					"account-id": aws.ToString(a.Id),
+					"state":      a.State,
+				}).Debug("Skip inactive account")
+				continue
+			}
+			accounts = append(accounts, Account{
+				AccountID:   aws.ToString(a.Id),
+				AccountName: aws.ToString(a.Name),
+			})
+		}
+	}
+
+	if len(ous) == 0 {
+		paginator := organizations.NewListAccountsPaginator(api, &organizations.ListAccountsInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			collect(page.Accounts)
+		}
+		return accounts, nil
+	}
+
+	for _, ou := range ous {
+		parents, err := listOrganizationalUnits(ctx, api, ou)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			paginator := organizations.NewListAccountsForParentPaginator(api,
+				&organizations.ListAccountsForParentInput{ParentId: aws.String(parent)})
+			for paginator.HasMorePages() {
+				page, err := paginator.NextPage(ctx)
+				if err != nil {
+					return nil, err
+				}
+				collect(page.Accounts)
+			}
+		}
+	}
+	return accounts, nil
+}
+
+// listOrganizationalUnits returns the given unit and all its descendants
+func listOrganizationalUnits(ctx context.Context, api OrganizationsAPI, ou string) ([]string, error) {
+	units := []string{ou}
+	children := []string{}
+	paginator := organizations.NewListOrganizationalUnitsForParentPaginator(api,
+		&organizations.ListOrganizationalUnitsForParentInput{ParentId: aws.String(ou)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't list children of %v: %w", ou, err)
+		}
+		for _, child := range page.OrganizationalUnits {
+			children = append(children, aws.ToString(child.Id))
+		}
+	}
+
+	for _, child := range children {
+		descendants, err := listOrganizationalUnits(ctx, api, child)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, descendants...)
+	}
+	return units, nil
+}
+
+// organizationIdentities returns an identity for every account in the
+// organization. The management account can't assume the organization
+// role in itself so the base identity is used directly for it.
+func organizationIdentities(
+	ctx context.Context, api OrganizationsAPI, base Identity, opts Options,
+) ([]Identity, error) {
+	tmpl, err := ParseRoleTemplate(opts.OrganizationRoleTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := api.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return nil, fmt.Errorf("can't describe the organization: %w", err)
+	}
+	managementAccount := aws.ToString(org.Organization.MasterAccountId)
+	orgARN, err := arn.Parse(aws.ToString(org.Organization.Arn))
+	if err != nil {
+		return nil, fmt.Errorf("can't find the partition of the organization: %w", err)
+	}
+
+	accounts, err := listAccounts(ctx, api, opts.OrganizationalUnits)
+	if err != nil {
+		return nil, fmt.Errorf("can't list the organization accounts: %w", err)
+	}
+
+	identities := make([]Identity, 0, len(accounts))
+	for _, account := range accounts {
+		account.Partition = orgARN.Partition
+		identity := base
+		identity.AccountID = account.AccountID
+		identity.AccountName = account.AccountName
+
+		if account.AccountID != managementAccount {
+			identity.RoleARN, err = renderRoleARN(tmpl, account)
+			if err != nil {
+				return nil, err
+			}
+			identity.RoleSessionName = opts.RoleSessionName
+			identity.RoleExternalID = opts.RoleExternalID
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
@@ -0,0 +1,213 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockOrganizationsClient struct {
+	ManagementAccount string
+	// Partition of the organization, aws by default
+	Partition string
+	// Accounts by the parent (root or organizational unit)
+	Accounts map[string][]types.Account
+	// Children organizational units by the parent
+	Children map[string][]string
+
+	ErrorOnList error
+}
+
+func (c *mockOrganizationsClient) DescribeOrganization(
+	_ context.Context, _ *organizations.DescribeOrganizationInput, _ ...func(*organizations.Options),
+) (*organizations.DescribeOrganizationOutput, error) {
+	partition := c.Partition
+	if partition == "" {
+		partition = "aws"
+	}
+	orgARN := fmt.Sprintf("arn:%v:organizations::%v:organization/o-sample", partition, c.ManagementAccount)
+	return &organizations.DescribeOrganizationOutput{
+		Organization: &types.Organization{
+			Arn:             aws.String(orgARN),
+			MasterAccountId: aws.String(c.ManagementAccount),
+		},
+	}, nil
+}
+
+func (c *mockOrganizationsClient) ListAccounts(
+	_ context.Context, _ *organizations.ListAccountsInput, _ ...func(*organizations.Options),
+) (*organizations.ListAccountsOutput, error) {
+	if c.ErrorOnList != nil {
+		return nil, c.ErrorOnList
+	}
+	accounts := []types.Account{}
+	for _, a := range c.Accounts {
+		accounts = append(accounts, a...)
+	}
+	return &organizations.ListAccountsOutput{Accounts: accounts}, nil
+}
+
+func (c *mockOrganizationsClient) ListAccountsForParent(
+	_ context.Context, input *organizations.ListAccountsForParentInput, _ ...func(*organizations.Options),
+) (*organizations.ListAccountsForParentOutput, error) {
+	return &organizations.ListAccountsForParentOutput{Accounts: c.Accounts[*input.ParentId]}, nil
+}
+
+func (c *mockOrganizationsClient) ListOrganizationalUnitsForParent(
+	_ context.Context, input *organizations.ListOrganizationalUnitsForParentInput, _ ...func(*organizations.Options),
+) (*organizations.ListOrganizationalUnitsForParentOutput, error) {
+	units := []types.OrganizationalUnit{}
+	for _, child := range c.Children[*input.ParentId] {
+		units = append(units, types.OrganizationalUnit{Id: aws.String(child)})
+	}
+	return &organizations.ListOrganizationalUnitsForParentOutput{OrganizationalUnits: units}, nil
+}
+
+func newMockAccount(id string, state types.AccountState) types.Account {
+	return types.Account{
+		Id:    aws.String(id),
+		Name:  aws.String(fmt.Sprintf("name-%v", id)),
+		State: state,
+	}
+}
+
+func newMockOrganization() *mockOrganizationsClient {
+	return &mockOrganizationsClient{
+		ManagementAccount: "000000000000",
+		Accounts: map[string][]types.Account{
+			"r-root": {
+				newMockAccount("000000000000", types.AccountStateActive),
+			},
+			"ou-prod": {
+				newMockAccount("111111111111", types.AccountStateActive),
+				newMockAccount("222222222222", types.AccountStateSuspended),
+			},
+			"ou-prod-eu": {
+				newMockAccount("333333333333", types.AccountStateActive),
+			},
+			"ou-dev": {
+				newMockAccount("444444444444", types.AccountStateActive),
+			},
+		},
+		Children: map[string][]string{
+			"r-root":  {"ou-prod", "ou-dev"},
+			"ou-prod": {"ou-prod-eu"},
+		},
+	}
+}
+
+func accountIDs(accounts []Account) []string {
+	ids := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		ids = append(ids, a.AccountID)
+	}
+	return ids
+}
+
+func TestListAccounts(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		OUs      []string
+		Expected []string
+	}{
+		{[]string{}, []string{"000000000000", "111111111111", "333333333333", "444444444444"}},
+		{[]string{"ou-prod"}, []string{"111111111111", "333333333333"}},
+		{[]string{"ou-prod-eu", "ou-dev"}, []string{"333333333333", "444444444444"}},
+		{[]string{"ou-empty"}, []string{}},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("OUs %v", tt.OUs)
+		t.Run(testname, func(t *testing.T) {
+			accounts, err := listAccounts(context.Background(), newMockOrganization(), tt.OUs)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.Expected, accountIDs(accounts))
+		})
+	}
+}
+
+func TestParseRoleTemplate(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Template string
+		Valid    bool
+	}{
+		{"ReadOnly", true},
+		{"", false},
+		{"arn:aws:iam::{{.AccountID}}:role/ReadOnly", true},
+		{"arn:{{.Partition}}:iam::{{.AccountID}}:role/ReadOnly", true},
+		{"arn:aws:iam::{{.AccountID}}:role/{{.AccountName}}-ReadOnly", true},
+		{"arn:aws:iam::{{.AccountID}:role/ReadOnly", false},
+		{"arn:aws:iam::{{.Missing}}:role/ReadOnly", false},
+		{"arn:aws:iam::{{.AccountID}}:user/ReadOnly", false},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%v valid %v", tt.Template, tt.Valid)
+		t.Run(testname, func(t *testing.T) {
+			_, err := ParseRoleTemplate(tt.Template)
+			if tt.Valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestOrganizationIdentities(t *testing.T) {
+	t.Parallel()
+	opts := Options{
+		OrganizationalUnits:      []string{"r-root"},
+		OrganizationRoleTemplate: "arn:aws:iam::{{.AccountID}}:role/ReadOnly",
+		RoleSessionName:          "session",
+	}
+	base := Identity{Profile: "management"}
+
+	identities, err := organizationIdentities(context.Background(), newMockOrganization(), base, opts)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Identity{
+		{Profile: "management", AccountID: "000000000000", AccountName: "name-000000000000"},
+		{
+			Profile: "management", AccountID: "111111111111", AccountName: "name-111111111111",
+			RoleARN: "arn:aws:iam::111111111111:role/ReadOnly", RoleSessionName: "session",
+		},
+		{
+			Profile: "management", AccountID: "333333333333", AccountName: "name-333333333333",
+			RoleARN: "arn:aws:iam::333333333333:role/ReadOnly", RoleSessionName: "session",
+		},
+		{
+			Profile: "management", AccountID: "444444444444", AccountName: "name-444444444444",
+			RoleARN: "arn:aws:iam::444444444444:role/ReadOnly", RoleSessionName: "session",
+		},
+	}, identities)
+}
+
+func TestOrganizationIdentitiesListFailure(t *testing.T) {
+	t.Parallel()
+	client := newMockOrganization()
+	client.ErrorOnList = fmt.Errorf("access denied")
+	opts := Options{OrganizationRoleTemplate: "ReadOnly"}
+
+	_, err := organizationIdentities(context.Background(), client, Identity{}, opts)
+	assert.Error(t, err)
+}
+
+func TestOrganizationIdentitiesPartition(t *testing.T) {
+	t.Parallel()
+	client := newMockOrganization()
+	client.Partition = "aws-cn"
+	opts := Options{OrganizationalUnits: []string{"r-root"}, OrganizationRoleTemplate: "ReadOnly"}
+
+	identities, err := organizationIdentities(context.Background(), client, Identity{}, opts)
+	assert.NoError(t, err)
+	assert.Contains(t, identities, Identity{
+		AccountID: "111111111111", AccountName: "name-111111111111",
+		RoleARN: "arn:aws-cn:iam::111111111111:role/ReadOnly",
+	})
+}
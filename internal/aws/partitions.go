@@ -0,0 +1,79 @@
+package aws
+
+type partition struct {
+	ID      string
+	Regions []string
+}
+
+// partitions is the static table of the known AWS partitions and their
+// regions, aws-sdk-go-v2 does not expose one. New regions can be found
+// without updating it with `--aws-enabled-regions`.
+var partitions = []partition{
+	{
+		ID: "aws",
+		Regions: []string{
+			"af-south-1",
+			"ap-east-1",
+			"ap-east-2",
+			"ap-northeast-1",
+			"ap-northeast-2",
+			"ap-northeast-3",
+			"ap-south-1",
+			"ap-south-2",
+			"ap-southeast-1",
+			"ap-southeast-2",
+			"ap-southeast-3",
+			"ap-southeast-4",
+			"ap-southeast-5",
+			"ap-southeast-6",
+			"ap-southeast-7",
+			"ca-central-1",
+			"ca-west-1",
+			"eu-central-1",
+			"eu-central-2",
+			"eu-north-1",
+			"eu-south-1",
+			"eu-south-2",
+			"eu-west-1",
+			"eu-west-2",
+			"eu-west-3",
+			"il-central-1",
+			"me-central-1",
+			"me-south-1",
+			"mx-central-1",
+			"sa-east-1",
+			"us-east-1",
+			"us-east-2",
+			"us-west-1",
+			"us-west-2",
+		},
+	},
+	{
+		ID:      "aws-cn",
+		Regions: []string{"cn-north-1", "cn-northwest-1"},
+	},
+	{
+		ID:      "aws-us-gov",
+		Regions: []string{"us-gov-east-1", "us-gov-west-1"},
+	},
+	{
+		ID:      "aws-iso",
+		Regions: []string{"us-iso-east-1", "us-iso-west-1"},
+	},
+	{
+		ID:      "aws-iso-b",
+		Regions: []string{"us-isob-east-1", "us-isob-west-1"},
+	},
+	{
+		ID:      "aws-iso-e",
+		Regions: []string{"eu-isoe-west-1"},
+	},
+	{
+		ID:      "aws-iso-f",
+		Regions: []string{"us-isof-east-1", "us-isof-south-1"},
+	},
+	{
+		ID:      "aws-eusc",
+		Regions: []string{"eusc-de-east-1"},
+	},
+}
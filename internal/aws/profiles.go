@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	configFileEnv      = "AWS_CONFIG_FILE"
+	credentialsFileEnv = "AWS_SHARED_CREDENTIALS_FILE"
+
+	defaultProfile       = "default"
+	configProfilePrefix  = "profile "
+	iniSectionStartToken = "["
+	iniSectionEndToken   = "]"
+)
+
+// GetProfiles will return all the named profiles defined in the
+// shared AWS config and credentials files
+func GetProfiles() ([]string, error) {
+	configFile := config.DefaultSharedConfigFilename()
+	if v := os.Getenv(configFileEnv); v != "" {
+		configFile = v
+	}
+	credentialsFile := config.DefaultSharedCredentialsFilename()
+	if v := os.Getenv(credentialsFileEnv); v != "" {
+		credentialsFile = v
+	}
+
+	fromConfig, err := readProfiles(configFile, true)
+	if err != nil {
+		return nil, err
+	}
+	fromCredentials, err := readProfiles(credentialsFile, false)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	profiles := make([]string, 0, len(fromConfig)+len(fromCredentials))
+	for _, p := range append(fromConfig, fromCredentials...) {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+func readProfiles(path string, isConfigFile bool) ([]string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"path": path,
+		}).Debug("Shared AWS file does not exist")
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProfiles(f, isConfigFile)
+}
+
+// parseProfiles extracts the profile names from an INI formatted
+// shared AWS file. In the config file all profiles except the default one
+// are prefixed with "profile ", other sections (sso-session, services)
+// are not profiles and are ignored.
+func parseProfiles(r io.Reader, isConfigFile bool) ([]string, error) {
+	profiles := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, iniSectionStartToken) || !strings.HasSuffix(line, iniSectionEndToken) {
+			continue
+		}
+		section := strings.TrimSpace(line[len(iniSectionStartToken) : len(line)-len(iniSectionEndToken)])
+
+		if !isConfigFile || section == defaultProfile {
+			if section != "" {
+				profiles = append(profiles, section)
+			}
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(section, configProfilePrefix); ok {
+			if name = strings.TrimSpace(name); name != "" {
+				profiles = append(profiles, name)
+			}
+		}
+	}
+	return profiles, scanner.Err()
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfiles(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		content      string
+		isConfigFile bool
+		expected     []string
+	}{
+		{"", true, []string{}},
+		{"[default]\nregion = us-east-1\n", true, []string{"default"}},
+		{"[default]\n[profile dev]\n[profile  prod ]\n", true, []string{"default", "dev", "prod"}},
+		{"[sso-session my-sso]\n[services local]\n[profile dev]\n", true, []string{"dev"}},
+		{"# [profile commented]\n[profile dev]\nkey = [value]\n", true, []string{"dev"}},
+		{"[default]\n[dev]\n", false, []string{"default", "dev"}},
+		{"[profile dev]\n", false, []string{"profile dev"}},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%q (config %v)", tt.content, tt.isConfigFile)
+		t.Run(testname, func(t *testing.T) {
+			profiles, err := parseProfiles(strings.NewReader(tt.content), tt.isConfigFile)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, profiles)
+		})
+	}
+}
+
+func TestGetProfiles(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	credentialsFile := filepath.Join(dir, "credentials")
+
+	if err := os.WriteFile(configFile, []byte("[default]\n[profile dev]\n[profile prod]\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.WriteFile(credentialsFile, []byte("[dev]\n[legacy]\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Setenv(configFileEnv, configFile)
+	t.Setenv(credentialsFileEnv, credentialsFile)
+
+	profiles, err := GetProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "dev", "legacy", "prod"}, profiles)
+}
+
+func TestGetProfilesMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(configFileEnv, filepath.Join(dir, "config"))
+	t.Setenv(credentialsFileEnv, filepath.Join(dir, "credentials"))
+
+	profiles, err := GetProfiles()
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+}
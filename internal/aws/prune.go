@@ -0,0 +1,127 @@
+// Package aws provides function for working with EKS cluseters
+package aws
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Scope is an identity and region combination
+type Scope struct {
+	Identity Identity
+	Region   string
+}
+
+// Discovery is the result of a search
+type Discovery struct {
+	Clusters []*cluster.Cluster
+	// Searched are the scopes searched without errors, a cluster
+	// missing from them does not exist anymore
+	Searched []Scope
+}
+
+// legacyPrefix is the prefix of the kubeconfig keys of EKS clusters, it
+// recognizes the entries written before the markers were added
+const legacyPrefix = cluster.UniqueIDPrefix + "arn:"
+
+// isOwned tells if the entry was written by kdiscover for an EKS cluster
+func isOwned(entry kubeconfig.Entry) bool {
+	if entry.Marker != nil {
+		return entry.Marker.Provider == cluster.AWS.String()
+	}
+	return strings.HasPrefix(entry.Key, legacyPrefix)
+}
+
+// entryScope is how a kubeconfig user authenticates, the account
+// comes from the role so it is not needed
+type entryScope struct {
+	profile string
+	role    string
+	region  string
+}
+
+// isAuthCommand tells if the command is one of the AWS authenticators,
+// the commands pinned to a path with --auth-command and kdiscover
+// installed as a kubectl plugin are recognized too
+func isAuthCommand(command string) bool {
+	base := filepath.Base(command)
+	if base == commandKdiscoverPlugin {
+		return true
+	}
+	for _, c := range commands {
+		if c == base {
+			return true
+		}
+	}
+	return false
+}
+
+func isRoleOption(arg string) bool {
+	for _, o := range roleOptions {
+		if o == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEntryScope reads the region, role and profile from the exec
+// config written by getConfigAuthInfo, the region is read from AWS_REGION
+// if set
+func parseEntryScope(authInfo *clientcmdapi.AuthInfo) (entryScope, bool) {
+	if authInfo == nil || authInfo.Exec == nil {
+		return entryScope{}, false
+	}
+
+	s := entryScope{}
+	// the arguments of the other authenticators are unknown, they
+	// need AWS_REGION in the environment
+	args := authInfo.Exec.Args
+	for i := 0; isAuthCommand(authInfo.Exec.Command) && i+1 < len(args); i++ {
+		switch {
+		case args[i] == "--region":
+			s.region = args[i+1]
+		case isRoleOption(args[i]):
+			s.role = args[i+1]
+		}
+	}
+	for _, env := range authInfo.Exec.Env {
+		switch env.Name {
+		case profileEnvVar:
+			s.profile = env.Value
+		case regionEnvVar:
+			s.region = env.Value
+		}
+	}
+	return s, s.region != ""
+}
+
+// Stale returns the kubeconfig entries written by kdiscover for EKS
+// clusters that were not found even if their identity and region were
+// searched. Entries of
+// regions that failed or were not searched are never stale.
+func (d *Discovery) Stale(entries []kubeconfig.Entry) []kubeconfig.Entry {
+	found := make(map[string]bool, len(d.Clusters))
+	for _, cls := range d.Clusters {
+		found[cls.GetUniqueID()] = true
+	}
+	searched := make(map[entryScope]bool, len(d.Searched))
+	for _, s := range d.Searched {
+		searched[entryScope{profile: s.Identity.Profile, role: s.Identity.RoleARN, region: s.Region}] = true
+	}
+
+	stale := []kubeconfig.Entry{}
+	for _, entry := range entries {
+		if found[entry.ID()] || !isOwned(entry) {
+			continue
+		}
+		if s, ok := parseEntryScope(entry.AuthInfo); ok && searched[s] {
+			stale = append(stale, entry)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,93 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/mateimicu/kdiscover/internal/kubeconfig"
+	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestStale(t *testing.T) {
+	t.Parallel()
+	dev := Identity{Profile: "dev"}
+	role := Identity{RoleARN: "arn:aws:iam::123456789012:role/admin"}
+
+	existing := newEKSCluster("existing", "eu-west-1", dev)
+	deleted := newEKSCluster("deleted", "eu-west-1", dev)
+	otherProfile := newEKSCluster("other-profile", "eu-west-1", Identity{Profile: "prod"})
+	notSearched := newEKSCluster("not-searched", "us-east-1", dev)
+	deletedWithRole := newEKSCluster("deleted-with-role", "eu-west-1", role)
+
+	k := kubeconfig.New()
+	for _, cls := range []*cluster.Cluster{existing, deleted, otherProfile, notSearched, deletedWithRole} {
+		k.AddCluster(cls, cls.Name)
+	}
+	// found clusters are recognized by their marker whatever their name
+	k.AddEntry(existing, kubeconfig.EntryNames{Cluster: "named", User: "named", Context: existing.Name})
+	manual := cluster.NewCluster()
+	manual.Name = "manual"
+	manual.GenerateAuthInfo = func(_ *cluster.Cluster) *clientcmdapi.AuthInfo {
+		return clientcmdapi.NewAuthInfo()
+	}
+	k.AddCluster(manual, "manual")
+	// entries of other providers are never stale
+	other := newEKSCluster("other-provider", "eu-west-1", dev)
+	other.Provider = cluster.Google
+	k.AddCluster(other, other.Name)
+
+	d := &Discovery{
+		Clusters: []*cluster.Cluster{existing},
+		Searched: []Scope{
+			{Identity: dev, Region: "eu-west-1"},
+			{Identity: role, Region: "eu-west-1"},
+		},
+	}
+
+	stale := d.Stale(k.Entries(""))
+	keys := []string{}
+	for _, entry := range stale {
+		keys = append(keys, entry.Key)
+	}
+	assert.ElementsMatch(t, []string{deleted.GetUniqueID(), deletedWithRole.GetUniqueID()}, keys)
+
+	// entries written before the markers are recognized by their key
+	legacy := k.Entries(deleted.GetUniqueID())
+	legacy[0].Marker = nil
+	assert.Len(t, d.Stale(legacy), 1)
+
+	assert.Empty(t, (&Discovery{Clusters: []*cluster.Cluster{}, Searched: []Scope{}}).Stale(k.Entries("")))
+}
+
+func TestParseEntryScope(t *testing.T) {
+	t.Parallel()
+	cls := newEKSCluster("name", "eu-west-1", Identity{Profile: "dev", RoleARN: "role"})
+	for _, authType := range []AuthType{useAWSCLI, useIAMAuthenticator, useKdiscover} {
+		s, ok := parseEntryScope(getConfigAuthInfo(cls, &Auth{Type: authType}))
+		assert.True(t, ok)
+		assert.Equal(t, entryScope{profile: "dev", role: "role", region: "eu-west-1"}, s)
+	}
+
+	_, ok := parseEntryScope(nil)
+	assert.False(t, ok)
+	_, ok = parseEntryScope(&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "gcloud"}})
+	assert.False(t, ok)
+
+	custom := &Auth{
+		Type:    useCustom,
+		Command: mustTemplate(t, "login"),
+		Args:    []*cluster.NameTemplate{mustTemplate(t, "--region"), mustTemplate(t, "us-east-1")},
+		Env:     []EnvTemplate{{Name: "AWS_REGION", Value: mustTemplate(t, "{{.Region}}")}},
+	}
+	s, ok := parseEntryScope(getConfigAuthInfo(cls, custom))
+	assert.True(t, ok)
+	assert.Equal(t, entryScope{profile: "dev", region: "eu-west-1"}, s)
+
+	for _, command := range []string{"/usr/local/bin/kdiscover", "kubectl-discover"} {
+		pinned := &Auth{Type: useKdiscover, Command: mustTemplate(t, command)}
+		s, ok = parseEntryScope(getConfigAuthInfo(cls, pinned))
+		assert.True(t, ok, command)
+		assert.Equal(t, entryScope{profile: "dev", role: "role", region: "eu-west-1"}, s)
+	}
+}
@@ -1,7 +1,26 @@
 package aws
 
 import (
-	"github.com/aws/aws-sdk-go/aws/endpoints" //nolint:staticcheck // aws-sdk-go v2 migration tracked separately
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const optInStatusFilter = "opt-in-status"
+
+var (
+	// regions that are enabled by default, used to query the enabled regions
+	partitionQueryRegions = map[string]string{
+		"aws":        "us-east-1",
+		"aws-cn":     "cn-north-1",
+		"aws-us-gov": "us-gov-west-1",
+	}
+	enabledOptInStatuses = []string{"opt-in-not-required", "opted-in"}
 )
 
 func contains(key string, list []string) bool {
@@ -13,21 +32,37 @@ func contains(key string, list []string) bool {
 	return false
 }
 
+// queryRegion returns a region of the partition that is enabled by default
+func (p partition) queryRegion() string {
+	if r, ok := partitionQueryRegions[p.ID]; ok {
+		return r
+	}
+	return p.Regions[0]
+}
+
+// credentialsRegion returns the region used to obtain the credentials
+// for the searched regions. STS is called at a regional endpoint so it
+// is a region of the same partition that is enabled by default, the
+// first searched region may be an opt-in region.
+func credentialsRegion(regions []string) string {
+	for _, p := range partitions {
+		if contains(regions[0], p.Regions) {
+			return p.queryRegion()
+		}
+	}
+	return regions[0]
+}
+
 // GetRegions will return all the available regions in the given
 // aws partitions
 func GetRegions(awsPartitions []string) []string {
 	var regions []string
-	resolver := endpoints.DefaultResolver()
-	partitions := resolver.(endpoints.EnumPartitions).Partitions()
 
 	for _, p := range partitions {
-		if !contains(p.ID(), awsPartitions) {
+		if !contains(p.ID, awsPartitions) {
 			continue
 		}
-
-		for id := range p.Regions() {
-			regions = append(regions, id)
-		}
+		regions = append(regions, p.Regions...)
 	}
 
 	return regions
@@ -36,12 +71,128 @@ func GetRegions(awsPartitions []string) []string {
 // AllowedParitions returns all the allowed AWS partitions
 func AllowedParitions() []string {
 	var allowedPartitions []string
-	resolver := endpoints.DefaultResolver()
-	partitions := resolver.(endpoints.EnumPartitions).Partitions()
 
 	for _, p := range partitions {
-		allowedPartitions = append(allowedPartitions, p.ID())
+		allowedPartitions = append(allowedPartitions, p.ID)
 	}
 
 	return allowedPartitions
 }
+
+// FilterRegions keeps the regions that match at least one of the include
+// patterns (all regions if there are none) and none of the exclude
+// patterns. Patterns use the shell glob syntax, for example "eu-*".
+func FilterRegions(regions, include, exclude []string) ([]string, error) {
+	for _, pattern := range append(include, exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid region pattern %q: %w", pattern, err)
+		}
+	}
+
+	filtered := []string{}
+	for _, region := range regions {
+		if len(include) != 0 && !matchesAny(region, include) {
+			continue
+		}
+		if matchesAny(region, exclude) {
+			continue
+		}
+		filtered = append(filtered, region)
+	}
+	return filtered, nil
+}
+
+func matchesAny(region string, patterns []string) bool {
+	for _, pattern := range patterns {
+		// patterns are validated before
+		if ok, _ := path.Match(pattern, region); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EC2API is the subset of the EC2 API used to find the enabled regions
+type EC2API interface {
+	DescribeRegions(context.Context, *ec2.DescribeRegionsInput, ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+}
+
+// GetEnabledRegions will return the regions enabled for the account of the
+// given profile in the aws partitions. The regions are queried with EC2
+// DescribeRegions, for the partitions where this fails the static
+// regions are used.
+func GetEnabledRegions(ctx context.Context, awsPartitions []string, profile string) []string {
+	var regions []string
+
+	for _, p := range partitions {
+		if !contains(p.ID, awsPartitions) || len(p.Regions) == 0 {
+			continue
+		}
+
+		enabled, err := describeEnabledRegions(ctx, profile, p.queryRegion())
+		if err != nil {
+			log.WithFields(log.Fields{
+				"partition": p.ID,
+				"profile":   profile,
+				"error":     err.Error(),
+			}).Warn("Can't describe the enabled regions, fallback on the static regions")
+			regions = append(regions, p.Regions...)
+			continue
+		}
+		regions = append(regions, enabled...)
+	}
+
+	return regions
+}
+
+// identityRegions returns the regions searched with the identity, with
+// EnabledRegions the ones that are not enabled for its account are
+// dropped. If the enabled regions can't be listed all the regions are
+// searched.
+func identityRegions(ctx context.Context, api EC2API, identity Identity, opts Options) []string {
+	if !opts.EnabledRegions {
+		return opts.Regions
+	}
+	enabled, err := listEnabledRegions(ctx, api)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"identity": identity.String(),
+			"error":    err.Error(),
+		}).Warn("Can't describe the enabled regions, search all the regions")
+		return opts.Regions
+	}
+	regions := []string{}
+	for _, region := range opts.Regions {
+		if contains(region, enabled) {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+func describeEnabledRegions(ctx context.Context, profile, region string) ([]string, error) {
+	cfg, err := newConfig(ctx, Identity{Profile: profile}, region)
+	if err != nil {
+		return nil, err
+	}
+	return listEnabledRegions(ctx, ec2.NewFromConfig(cfg))
+}
+
+func listEnabledRegions(ctx context.Context, api EC2API) ([]string, error) {
+	out, err := api.DescribeRegions(ctx, &ec2.DescribeRegionsInput{
+		AllRegions: aws.Bool(true),
+		Filters: []types.Filter{{
+			Name:   aws.String(optInStatusFilter),
+			Values: enabledOptInStatuses,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make([]string, 0, len(out.Regions))
+	for _, r := range out.Regions {
+		regions = append(regions, aws.ToString(r.RegionName))
+	}
+	return regions, nil
+}
@@ -1,10 +1,14 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,3 +64,109 @@ func TestGetRegions(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterRegions(t *testing.T) {
+	t.Parallel()
+	regions := []string{"eu-west-1", "eu-central-1", "us-east-1", "us-west-2", "ap-south-1"}
+	tts := []struct {
+		include, exclude []string
+		expected         []string
+	}{
+		{[]string{}, []string{}, regions},
+		{[]string{"eu-*"}, []string{}, []string{"eu-west-1", "eu-central-1"}},
+		{[]string{"eu-*", "us-east-1"}, []string{}, []string{"eu-west-1", "eu-central-1", "us-east-1"}},
+		{[]string{}, []string{"ap-*", "us-*"}, []string{"eu-west-1", "eu-central-1"}},
+		{[]string{"*-1"}, []string{"eu-central-?"}, []string{"eu-west-1", "us-east-1", "ap-south-1"}},
+		{[]string{"sa-*"}, []string{}, []string{}},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("include %v exclude %v", tt.include, tt.exclude)
+		t.Run(testname, func(t *testing.T) {
+			result, err := FilterRegions(regions, tt.include, tt.exclude)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestFilterRegionsBadPattern(t *testing.T) {
+	t.Parallel()
+	_, err := FilterRegions([]string{"eu-west-1"}, []string{"eu-["}, []string{})
+	assert.Error(t, err)
+
+	_, err = FilterRegions([]string{"eu-west-1"}, []string{}, []string{"eu-["})
+	assert.Error(t, err)
+}
+
+func TestCredentialsRegion(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Regions  []string
+		Expected string
+	}{
+		{[]string{"af-south-1", "eu-west-1"}, "us-east-1"},
+		{[]string{"cn-northwest-1"}, "cn-north-1"},
+		{[]string{"us-gov-east-1"}, "us-gov-west-1"},
+		{[]string{"us-iso-west-1"}, "us-iso-east-1"},
+		// not in the static table, found with --aws-enabled-regions
+		{[]string{"xx-new-1"}, "xx-new-1"},
+	}
+	for _, tt := range tts {
+		assert.Equal(t, tt.Expected, credentialsRegion(tt.Regions), tt.Regions)
+	}
+}
+
+func TestIdentityRegions(t *testing.T) {
+	t.Parallel()
+	client := &mockEC2Client{Regions: []types.Region{
+		{RegionName: aws.String("eu-west-1")},
+		{RegionName: aws.String("us-east-1")},
+	}}
+	opts := Options{Regions: []string{"af-south-1", "eu-west-1", "us-east-1"}}
+	ctx := context.Background()
+	assert.Equal(t, opts.Regions, identityRegions(ctx, client, Identity{}, opts))
+
+	opts.EnabledRegions = true
+	assert.Equal(t, []string{"eu-west-1", "us-east-1"}, identityRegions(ctx, client, Identity{}, opts))
+
+	// all the regions are searched if the enabled regions are unknown
+	failing := &mockEC2Client{Err: fmt.Errorf("access denied")}
+	assert.Equal(t, opts.Regions, identityRegions(ctx, failing, Identity{}, opts))
+}
+
+type mockEC2Client struct {
+	Regions []types.Region
+	Err     error
+	Input   *ec2.DescribeRegionsInput
+}
+
+func (c *mockEC2Client) DescribeRegions(
+	_ context.Context, input *ec2.DescribeRegionsInput, _ ...func(*ec2.Options),
+) (*ec2.DescribeRegionsOutput, error) {
+	c.Input = input
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return &ec2.DescribeRegionsOutput{Regions: c.Regions}, nil
+}
+
+func TestListEnabledRegions(t *testing.T) {
+	t.Parallel()
+	client := &mockEC2Client{Regions: []types.Region{
+		{RegionName: aws.String("eu-west-1"), OptInStatus: aws.String("opt-in-not-required")},
+		{RegionName: aws.String("eu-south-1"), OptInStatus: aws.String("opted-in")},
+	}}
+
+	regions, err := listEnabledRegions(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eu-west-1", "eu-south-1"}, regions)
+
+	// only the enabled regions are requested
+	assert.Len(t, client.Input.Filters, 1)
+	assert.Equal(t, optInStatusFilter, *client.Input.Filters[0].Name)
+	assert.ElementsMatch(t, enabledOptInStatuses, client.Input.Filters[0].Values)
+
+	_, err = listEnabledRegions(context.Background(), &mockEC2Client{Err: fmt.Errorf("access denied")})
+	assert.Error(t, err)
+}
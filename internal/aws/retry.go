@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultConcurrency is the number of DescribeCluster calls done
+	// in parallel across all the regions
+	DefaultConcurrency = 10
+
+	defaultBackoffBase        = 200 * time.Millisecond
+	defaultBackoffMax         = 10 * time.Second
+	defaultBackoffMaxAttempts = 6
+)
+
+// Limiter bounds the number of API calls done in parallel, it is shared
+// between the clients of all the regions
+type Limiter chan struct{}
+
+// NewLimiter creates a limiter that allows size calls in parallel
+func NewLimiter(size int) Limiter {
+	return make(Limiter, max(size, 1))
+}
+
+// acquire blocks until a slot is free or the context is done
+func (l Limiter) acquire(ctx context.Context) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l Limiter) release() {
+	<-l
+}
+
+// Backoff retries throttled calls with exponential delays and full jitter.
+// The SDK retryer runs first, this handles the throttling that outlasts it.
+type Backoff struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+func defaultBackoff() Backoff {
+	return Backoff{
+		Base:        defaultBackoffBase,
+		Max:         defaultBackoffMax,
+		MaxAttempts: defaultBackoffMaxAttempts,
+	}
+}
+
+// delay returns a random duration up to Base * 2^attempt capped at Max
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Max
+	if attempt < 32 && b.Base<<attempt > 0 && b.Base<<attempt < b.Max {
+		d = b.Base << attempt
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// regionMetrics counts the API calls made for a region, the attempts
+// retried by the SDK retryer included
+type regionMetrics struct {
+	listCalls     atomic.Int64
+	describeCalls atomic.Int64
+	throttled     atomic.Int64
+	retries       atomic.Int64
+}
+
+func (m *regionMetrics) fields() log.Fields {
+	return log.Fields{
+		"list-calls":     m.listCalls.Load(),
+		"describe-calls": m.describeCalls.Load(),
+		"throttled":      m.throttled.Load(),
+		"retries":        m.retries.Load(),
+	}
+}
+
+type regionMetricsKey struct{}
+
+// withRegionMetrics returns a context whose API calls are counted in the
+// metrics by the retryer of the EKS clients
+func withRegionMetrics(ctx context.Context, metrics *regionMetrics) context.Context {
+	return context.WithValue(ctx, regionMetricsKey{}, metrics)
+}
+
+// countingRetryer counts the attempts retried by the SDK retryer in the
+// metrics of the context, they happen before the Backoff sees an error
+type countingRetryer struct {
+	aws.RetryerV2
+}
+
+// newCountingRetryer wraps the retryer of a client, the SDK retryers
+// all implement RetryerV2
+func newCountingRetryer(r aws.Retryer) aws.Retryer {
+	if v2, ok := r.(aws.RetryerV2); ok {
+		return countingRetryer{RetryerV2: v2}
+	}
+	return r
+}
+
+// GetRetryToken is called by the SDK before every retry
+func (r countingRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	release, err := r.RetryerV2.GetRetryToken(ctx, opErr)
+	metrics, ok := ctx.Value(regionMetricsKey{}).(*regionMetrics)
+	if err != nil || !ok {
+		return release, err
+	}
+	metrics.retries.Add(1)
+	if ClassifyError(opErr) == CauseThrottling {
+		metrics.throttled.Add(1)
+	}
+	switch awsmiddleware.GetOperationName(ctx) {
+	case "ListClusters":
+		metrics.listCalls.Add(1)
+	case "DescribeCluster":
+		metrics.describeCalls.Add(1)
+	}
+	return release, nil
+}
+
+// retry calls fn until it succeeds, fails with an error other than
+// throttling or the attempts are exhausted
+func (b Backoff) retry(ctx context.Context, metrics *regionMetrics, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || ClassifyError(err) != CauseThrottling {
+			return err
+		}
+		metrics.throttled.Add(1)
+		if attempt+1 >= b.MaxAttempts {
+			return err
+		}
+
+		metrics.retries.Add(1)
+		delay := b.delay(attempt)
+		log.WithFields(log.Fields{
+			"attempt": attempt + 1,
+			"delay":   delay,
+			"err":     err,
+		}).Debug("Throttled, retry")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
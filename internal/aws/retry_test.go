@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	t.Parallel()
+	b := Backoff{Base: 100 * time.Millisecond, Max: time.Second, MaxAttempts: 5}
+	tts := []struct {
+		Attempt int
+		Limit   time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{100, time.Second},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("attempt %v at most %v", tt.Attempt, tt.Limit)
+		t.Run(testname, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := b.delay(tt.Attempt)
+				assert.GreaterOrEqual(t, d, time.Duration(0))
+				assert.LessOrEqual(t, d, tt.Limit)
+			}
+		})
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	t.Parallel()
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	tts := []struct {
+		Errors           []error
+		ExpectedCalls    int
+		ExpectedRetries  int64
+		ExpectedFailure  bool
+		ExpectedThrottle int64
+	}{
+		{[]error{nil}, 1, 0, false, 0},
+		{[]error{throttled, nil}, 2, 1, false, 1},
+		{[]error{throttled, throttled, throttled}, 3, 2, true, 3},
+		{[]error{errors.New("boom")}, 1, 0, true, 0},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("errors %v", tt.Errors)
+		t.Run(testname, func(t *testing.T) {
+			b := Backoff{Base: time.Millisecond, Max: time.Millisecond, MaxAttempts: 3}
+			metrics := &regionMetrics{}
+			calls := 0
+			err := b.retry(context.Background(), metrics, func() error {
+				err := tt.Errors[calls]
+				calls++
+				return err
+			})
+
+			assert.Equal(t, tt.ExpectedFailure, err != nil)
+			assert.Equal(t, tt.ExpectedCalls, calls)
+			assert.Equal(t, tt.ExpectedRetries, metrics.retries.Load())
+			assert.Equal(t, tt.ExpectedThrottle, metrics.throttled.Load())
+		})
+	}
+}
+
+func TestBackoffRetryCanceled(t *testing.T) {
+	t.Parallel()
+	b := Backoff{Base: time.Hour, Max: time.Hour, MaxAttempts: 3}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := b.retry(ctx, &regionMetrics{}, func() error {
+		return &smithy.GenericAPIError{Code: "ThrottlingException"}
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCountingRetryer(t *testing.T) {
+	t.Parallel()
+	throttled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if throttled < 2 {
+			throttled++
+			w.Header().Set("X-Amzn-Errortype", "ThrottlingException")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"message":"Rate exceeded"}`)
+			return
+		}
+		fmt.Fprint(w, `{"clusters":["prod"]}`)
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		},
+	}
+	client := NewEKS(cfg, "eu-west-1", Identity{})
+	metrics := &regionMetrics{}
+	_, err := client.EKS.ListClusters(withRegionMetrics(context.Background(), metrics), &eks.ListClustersInput{})
+	assert.NoError(t, err)
+	// the SDK retried twice, the first attempt is counted by the caller
+	assert.Equal(t, int64(2), metrics.retries.Load())
+	assert.Equal(t, int64(2), metrics.throttled.Load())
+	assert.Equal(t, int64(2), metrics.listCalls.Load())
+	assert.Equal(t, int64(0), metrics.describeCalls.Load())
+}
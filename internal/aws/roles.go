@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	iamService   = "iam"
+	roleResource = "role/"
+	commentToken = "#"
+)
+
+// ValidateRoleARN checks that the value is the ARN of an IAM role
+func ValidateRoleARN(value string) error {
+	a, err := arn.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid role ARN %q: %w", value, err)
+	}
+	if a.Service != iamService || !strings.HasPrefix(a.Resource, roleResource) {
+		return fmt.Errorf("invalid role ARN %q: not an IAM role", value)
+	}
+	return nil
+}
+
+// ReadRoleARNs reads the role ARNs from a file with one role ARN per
+// line, empty lines and lines starting with # are ignored
+func ReadRoleARNs(path string) ([]string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseRoleARNs(f)
+}
+
+func parseRoleARNs(r io.Reader) ([]string, error) {
+	roles := []string{}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, commentToken) {
+			continue
+		}
+		if err := ValidateRoleARN(line); err != nil {
+			return nil, fmt.Errorf("line %v (expected one role ARN per line): %w", lineNo, err)
+		}
+		roles = append(roles, line)
+	}
+	return roles, scanner.Err()
+}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRoleARN(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		value string
+		valid bool
+	}{
+		{"arn:aws:iam::123456789012:role/ReadOnly", true},
+		{"arn:aws:iam::123456789012:role/path/ReadOnly", true},
+		{"arn:aws-cn:iam::123456789012:role/ReadOnly", true},
+		{"arn:aws:iam::123456789012:user/ReadOnly", false},
+		{"arn:aws:eks:us-east-1:123456789012:cluster/prod", false},
+		{"ReadOnly", false},
+		{"", false},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%q valid %v", tt.value, tt.valid)
+		t.Run(testname, func(t *testing.T) {
+			err := ValidateRoleARN(tt.value)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseRoleARNs(t *testing.T) {
+	t.Parallel()
+	content := `
+# hub account
+arn:aws:iam::111111111111:role/ReadOnly
+
+  arn:aws:iam::222222222222:role/ReadOnly
+`
+	roles, err := parseRoleARNs(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"arn:aws:iam::111111111111:role/ReadOnly",
+		"arn:aws:iam::222222222222:role/ReadOnly",
+	}, roles)
+
+	_, err = parseRoleARNs(strings.NewReader("arn:aws:iam::111111111111:role/ReadOnly\nnot-an-arn\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReadRoleARNs(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "roles")
+	if err := os.WriteFile(path, []byte("arn:aws:iam::111111111111:role/ReadOnly\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	roles, err := ReadRoleARNs(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"arn:aws:iam::111111111111:role/ReadOnly"}, roles)
+
+	_, err = ReadRoleARNs(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	tokenPrefix     = "k8s-aws-v1."
+	clusterIDHeader = "x-k8s-aws-id"
+	// presignedURLExpiration is the X-Amz-Expires of the presigned URL,
+	// EKS accepts the token for 15 minutes regardless of it
+	presignedURLExpiration = "60"
+	// tokenExpiration is less than the 15 minutes so the token is renewed
+	// before EKS rejects it
+	tokenExpiration = 14 * time.Minute
+	// emptyPayloadHash is the SHA-256 of the empty body of the GET request
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Token is a bearer token accepted by the EKS clusters
+type Token struct {
+	Token      string
+	Expiration time.Time
+}
+
+// GetToken generates a token for the cluster with the credentials of
+// the identity, like `aws eks get-token`
+func GetToken(ctx context.Context, identity Identity, clusterName, region string) (Token, error) {
+	cfg, err := newConfig(ctx, identity, region)
+	if err != nil {
+		return Token{}, err
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return Token{}, fmt.Errorf("can't get the credentials of %v: %w", identity, err)
+	}
+	return presignToken(ctx, creds, clusterName, region, time.Now())
+}
+
+// presignToken encodes an sts:GetCallerIdentity URL presigned at the
+// signing time with the name of the cluster in a signed header. EKS
+// calls the URL to find out who the token belongs to.
+func presignToken(
+	ctx context.Context, creds aws.Credentials, clusterName, region string, signingTime time.Time,
+) (Token, error) {
+	endpoint, err := sts.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, sts.EndpointParameters{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return Token{}, fmt.Errorf("can't find the STS endpoint of %v: %w", region, err)
+	}
+
+	u := endpoint.URI
+	u.Path = "/"
+	u.RawQuery = url.Values{
+		"Action":        {"GetCallerIdentity"},
+		"Version":       {"2011-06-15"},
+		"X-Amz-Expires": {presignedURLExpiration},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set(clusterIDHeader, clusterName)
+
+	signed, _, err := v4.NewSigner().PresignHTTP(ctx, creds, req, emptyPayloadHash, "sts", region, signingTime)
+	if err != nil {
+		return Token{}, fmt.Errorf("can't presign the token: %w", err)
+	}
+	return Token{
+		Token:      tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(signed)),
+		Expiration: signingTime.Add(tokenExpiration),
+	}, nil
+}
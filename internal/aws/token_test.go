@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func staticCredentials(t *testing.T) aws.Credentials {
+	t.Helper()
+	creds, err := credentials.NewStaticCredentialsProvider("AKIDEXAMPLE", "secret", "").Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("can't retrieve static credentials: %v", err)
+	}
+	return creds
+}
+
+func decodeToken(t *testing.T, token string) *url.URL {
+	t.Helper()
+	encoded, ok := strings.CutPrefix(token, tokenPrefix)
+	assert.True(t, ok, "token %q without prefix", token)
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("can't decode token: %v", err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatalf("can't parse presigned URL: %v", err)
+	}
+	return u
+}
+
+func TestPresignToken(t *testing.T) {
+	t.Parallel()
+	signingTime := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tts := []struct {
+		Region, Host string
+	}{
+		{"eu-west-1", "sts.eu-west-1.amazonaws.com"},
+		{"cn-north-1", "sts.cn-north-1.amazonaws.com.cn"},
+	}
+
+	for _, tt := range tts {
+		t.Run(tt.Region, func(t *testing.T) {
+			token, err := presignToken(context.Background(), staticCredentials(t), "prod", tt.Region, signingTime)
+			assert.NoError(t, err)
+			assert.Equal(t, signingTime.Add(14*time.Minute), token.Expiration)
+
+			u := decodeToken(t, token.Token)
+			assert.Equal(t, "https", u.Scheme)
+			assert.Equal(t, tt.Host, u.Host)
+			q := u.Query()
+			assert.Equal(t, "GetCallerIdentity", q.Get("Action"))
+			assert.Equal(t, "60", q.Get("X-Amz-Expires"))
+			assert.Equal(t, "20240101T120000Z", q.Get("X-Amz-Date"))
+			assert.Equal(t, "AKIDEXAMPLE/20240101/"+tt.Region+"/sts/aws4_request", q.Get("X-Amz-Credential"))
+			assert.Equal(t, "host;x-k8s-aws-id", q.Get("X-Amz-SignedHeaders"))
+			assert.Len(t, q.Get("X-Amz-Signature"), 64)
+
+			// the same inputs are signed the same way
+			again, err := presignToken(context.Background(), staticCredentials(t), "prod", tt.Region, signingTime)
+			assert.NoError(t, err)
+			assert.Equal(t, token, again)
+			other, err := presignToken(context.Background(), staticCredentials(t), "dev", tt.Region, signingTime)
+			assert.NoError(t, err)
+			assert.NotEqual(t, q.Get("X-Amz-Signature"), decodeToken(t, other.Token).Query().Get("X-Amz-Signature"))
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package backup keeps timestamped copies of a kubeconfig
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxBackups is the number of backups kept for a kubeconfig
+	DefaultMaxBackups = 10
+
+	dirName  = "kdiscover-backups"
+	dirPerm  = 0o700
+	filePerm = 0o600
+	// idFormat sorts like the time and is safe in file names
+	idFormat = "20060102T150405.000Z"
+	fileExt  = ".yaml"
+
+	maxCreateAttempts = 100
+)
+
+// ErrNotFound is returned when no backup has the ID
+var ErrNotFound = errors.New("backup not found")
+
+// Backup is a copy of the kubeconfig taken before an update
+type Backup struct {
+	ID        string
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// Store keeps the backups of one kubeconfig, the backups of other
+// kubeconfigs can share the directory
+type Store struct {
+	Dir string
+	// Name is the file name of the kubeconfig, it prefixes the backups
+	Name string
+
+	now func() time.Time
+}
+
+// DefaultDir is a directory next to the kubeconfig
+func DefaultDir(kubeconfigPath string) string {
+	return filepath.Join(filepath.Dir(kubeconfigPath), dirName)
+}
+
+// New returns the store for the backups of the kubeconfig
+func New(dir, kubeconfigPath string) *Store {
+	return &Store{Dir: dir, Name: filepath.Base(kubeconfigPath), now: time.Now}
+}
+
+func (s *Store) prefix() string {
+	return s.Name + "-"
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, s.prefix()+id+fileExt)
+}
+
+// Create copies the kubeconfig in a new backup
+func (s *Store) Create(kubeconfigPath string) (*Backup, error) {
+	if err := os.MkdirAll(s.Dir, dirPerm); err != nil {
+		return nil, err
+	}
+	source, err := os.Open(filepath.Clean(kubeconfigPath))
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	createdAt := s.now().UTC().Truncate(time.Millisecond)
+	id := createdAt.Format(idFormat)
+	// O_EXCL so a backup is never overwritten, even by a concurrent run,
+	// a backup taken in the same millisecond moves to the next one
+	destination, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm)
+	for attempt := 1; os.IsExist(err) && attempt < maxCreateAttempts; attempt++ {
+		createdAt = createdAt.Add(time.Millisecond)
+		id = createdAt.Format(idFormat)
+		destination, err = os.OpenFile(s.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, filePerm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't create backup %v: %w", id, err)
+	}
+	size, err := io.Copy(destination, source)
+	if err == nil {
+		err = destination.Sync()
+	}
+	if closeErr := destination.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(s.path(id))
+		return nil, err
+	}
+	return &Backup{ID: id, Path: s.path(id), CreatedAt: createdAt, Size: size}, nil
+}
+
+// List returns the backups of the kubeconfig, newest first
+func (s *Store) List() ([]*Backup, error) {
+	files, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return []*Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []*Backup{}
+	for _, f := range files {
+		id, ok := strings.CutPrefix(f.Name(), s.prefix())
+		if !ok || f.IsDir() {
+			continue
+		}
+		id, ok = strings.CutSuffix(id, fileExt)
+		if !ok {
+			continue
+		}
+		createdAt, err := time.Parse(idFormat, id)
+		if err != nil {
+			// the backup of another kubeconfig with a longer name
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, &Backup{ID: id, Path: s.path(id), CreatedAt: createdAt, Size: info.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Get returns the backup with the ID, an unique prefix of the ID is
+// also accepted
+func (s *Store) Get(id string) (*Backup, error) {
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	matches := []*Backup{}
+	for _, b := range backups {
+		if b.ID == id {
+			return b, nil
+		}
+		if id != "" && strings.HasPrefix(b.ID, id) {
+			matches = append(matches, b)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %v in %v", ErrNotFound, id, s.Dir)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("backup ID %v is ambiguous, it matches %v backups", id, len(matches))
+}
+
+// Prune removes the oldest backups over max, zero keeps all of them.
+// The removed backups are returned.
+func (s *Store) Prune(maxBackups int) ([]*Backup, error) {
+	backups, err := s.List()
+	if err != nil || maxBackups <= 0 || len(backups) <= maxBackups {
+		return []*Backup{}, err
+	}
+	removed := backups[maxBackups:]
+	for _, b := range removed {
+		if err := os.Remove(b.Path); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T) (*Store, string, *time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	kubeconfigPath := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(kubeconfigPath, []byte("v0"), filePerm))
+
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	s := New(DefaultDir(kubeconfigPath), kubeconfigPath)
+	s.now = func() time.Time { return now }
+	return s, kubeconfigPath, &now
+}
+
+func createBackups(t *testing.T, s *Store, kubeconfigPath string, now *time.Time, count int) []*Backup {
+	t.Helper()
+	backups := []*Backup{}
+	for i := 0; i < count; i++ {
+		assert.NoError(t, os.WriteFile(kubeconfigPath, []byte(fmt.Sprintf("v%v", i)), filePerm))
+		b, err := s.Create(kubeconfigPath)
+		assert.NoError(t, err)
+		backups = append(backups, b)
+		*now = now.Add(time.Minute)
+	}
+	return backups
+}
+
+func TestCreateList(t *testing.T) {
+	t.Parallel()
+	s, kubeconfigPath, now := newTestStore(t)
+	backups := createBackups(t, s, kubeconfigPath, now, 3)
+
+	assert.Equal(t, "20240301T120000.000Z", backups[0].ID)
+	assert.Equal(t,
+		filepath.Join(filepath.Dir(kubeconfigPath), dirName, "config-20240301T120000.000Z.yaml"), backups[0].Path)
+	data, err := os.ReadFile(backups[1].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// backups of other kubeconfigs in the same directory are ignored
+	other := New(s.Dir, filepath.Join(filepath.Dir(kubeconfigPath), "config-prod"))
+	other.now = s.now
+	_, err = other.Create(kubeconfigPath)
+	assert.NoError(t, err)
+
+	listed, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Backup{backups[2], backups[1], backups[0]}, listed)
+}
+
+func TestCreateSameTime(t *testing.T) {
+	t.Parallel()
+	s, kubeconfigPath, _ := newTestStore(t)
+	first, err := s.Create(kubeconfigPath)
+	assert.NoError(t, err)
+	second, err := s.Create(kubeconfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "20240301T120000.001Z", second.ID)
+	assert.FileExists(t, first.Path)
+}
+
+func TestListMissingDir(t *testing.T) {
+	t.Parallel()
+	s := New(filepath.Join(t.TempDir(), "missing"), "config")
+	backups, err := s.List()
+	assert.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	s, kubeconfigPath, now := newTestStore(t)
+	backups := createBackups(t, s, kubeconfigPath, now, 2)
+
+	b, err := s.Get(backups[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, backups[1], b)
+
+	b, err = s.Get("20240301T1201")
+	assert.NoError(t, err)
+	assert.Equal(t, backups[1], b)
+
+	_, err = s.Get("20240301T120")
+	assert.ErrorContains(t, err, "ambiguous")
+	_, err = s.Get("2023")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get("")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+	s, kubeconfigPath, now := newTestStore(t)
+	backups := createBackups(t, s, kubeconfigPath, now, 5)
+
+	removed, err := s.Prune(0)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = s.Prune(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Backup{backups[2], backups[1], backups[0]}, removed)
+	for _, b := range removed {
+		assert.NoFileExists(t, b.Path)
+	}
+
+	listed, err := s.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Backup{backups[4], backups[3]}, listed)
+}
@@ -0,0 +1,205 @@
+// Package cache stores the discovered clusters on disk
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultTTL is how long the discovered clusters are used
+	DefaultTTL = time.Hour
+
+	appName       = "kdiscover"
+	entryVersion  = 3
+	entryFileExt  = ".json"
+	dirPermission = 0o700
+	filePerm      = 0o600
+)
+
+// Key identifies the clusters found by one identity in one region
+type Key struct {
+	Provider string
+	Identity string
+	Region   string
+}
+
+func (k Key) fileName() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{k.Provider, k.Identity, k.Region}, "\x00")))
+	return hex.EncodeToString(sum[:]) + entryFileExt
+}
+
+// Entry is the content of a cache file
+type Entry struct {
+	Version  int
+	Key      Key
+	StoredAt time.Time
+	Clusters []*cluster.Cluster
+}
+
+// Cache is a directory with one file for every key
+type Cache struct {
+	Dir string
+	TTL time.Duration
+
+	now func() time.Time
+}
+
+// DefaultDir is kdiscover under the user cache directory
+// ($XDG_CACHE_HOME on Linux)
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// New creates a cache in the given directory, the directory is
+// created on the first write
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl, now: time.Now}
+}
+
+// Age is the time passed since the entry was stored
+func (c *Cache) Age(e *Entry) time.Duration {
+	return c.now().Sub(e.StoredAt)
+}
+
+// Expired is true if the entry is older than the TTL
+func (c *Cache) Expired(e *Entry) bool {
+	return c.Age(e) > c.TTL
+}
+
+// Get returns the clusters stored for the key if they are not expired
+func (c *Cache) Get(key Key) ([]*cluster.Cluster, bool) {
+	e, err := c.read(filepath.Join(c.Dir, key.fileName()))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.WithFields(log.Fields{
+				"key": key,
+				"err": err,
+			}).Debug("Can't read cache entry")
+		}
+		return nil, false
+	}
+	if e.Key != key || c.Expired(e) {
+		return nil, false
+	}
+	return e.Clusters, true
+}
+
+// Put stores the clusters for the key
+func (c *Cache) Put(key Key, clusters []*cluster.Cluster) error {
+	if err := os.MkdirAll(c.Dir, dirPermission); err != nil {
+		return err
+	}
+	data, err := json.Marshal(Entry{
+		Version:  entryVersion,
+		Key:      key,
+		StoredAt: c.now(),
+		Clusters: clusters,
+	})
+	if err != nil {
+		return err
+	}
+
+	// write in a temporary file so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.Dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), filePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.Dir, key.fileName()))
+}
+
+// Entries returns all the entries from the cache, including the
+// expired ones, sorted by key
+func (c *Cache) Entries() ([]*Entry, error) {
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(files))
+	for _, f := range files {
+		e, err := c.read(f)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path": f,
+				"err":  err,
+			}).Debug("Skip invalid cache entry")
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Key, entries[j].Key
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Identity != b.Identity {
+			return a.Identity < b.Identity
+		}
+		return a.Region < b.Region
+	})
+	return entries, nil
+}
+
+// Clear removes all the entries and returns how many were removed
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return 0, err
+		}
+	}
+	return len(files), nil
+}
+
+func (c *Cache) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*"+entryFileExt))
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (c *Cache) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Version != entryVersion {
+		return nil, fmt.Errorf("unsupported cache entry version %v", e.Version)
+	}
+	return &e, nil
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCache(t *testing.T, now *time.Time) *Cache {
+	c := New(filepath.Join(t.TempDir(), "kdiscover"), time.Hour)
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+func TestGetPut(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Clusters int
+		Age      time.Duration
+		Found    bool
+	}{
+		{0, 0, true},
+		{3, 0, true},
+		{3, 59 * time.Minute, true},
+		{3, 61 * time.Minute, false},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%v clusters after %v", tt.Clusters, tt.Age)
+		t.Run(testname, func(t *testing.T) {
+			now := time.Now()
+			c := newTestCache(t, &now)
+			key := Key{Provider: "aws", Identity: "profile dev", Region: "eu-west-1"}
+			clusters := cluster.GetMockClusters(tt.Clusters)
+
+			assert.NoError(t, c.Put(key, clusters))
+			now = now.Add(tt.Age)
+
+			cached, found := c.Get(key)
+			assert.Equal(t, tt.Found, found)
+			if !tt.Found {
+				return
+			}
+			assert.Len(t, cached, len(clusters))
+			for i, cls := range cached {
+				assert.Equal(t, clusters[i].GetUniqueID(), cls.GetUniqueID())
+				assert.Equal(t, clusters[i].CertificateAuthorityData, cls.CertificateAuthorityData)
+				assert.NotNil(t, cls.GenerateClusterConfig)
+			}
+		})
+	}
+}
+
+func TestGetMissing(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	c := newTestCache(t, &now)
+	key := Key{Provider: "aws", Identity: "profile dev", Region: "eu-west-1"}
+	assert.NoError(t, c.Put(key, cluster.GetMockClusters(1)))
+
+	for _, other := range []Key{
+		{Provider: "aws", Identity: "profile prod", Region: "eu-west-1"},
+		{Provider: "aws", Identity: "profile dev", Region: "us-east-1"},
+	} {
+		_, found := c.Get(other)
+		assert.False(t, found, other)
+	}
+}
+
+func TestGetCorrupted(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	c := newTestCache(t, &now)
+	key := Key{Provider: "aws", Region: "eu-west-1"}
+	assert.NoError(t, os.MkdirAll(c.Dir, dirPermission))
+	assert.NoError(t, os.WriteFile(filepath.Join(c.Dir, key.fileName()), []byte("{"), filePerm))
+
+	_, found := c.Get(key)
+	assert.False(t, found)
+}
+
+func TestEntriesClear(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	c := newTestCache(t, &now)
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	regions := []string{"us-east-1", "eu-west-1", "ap-south-1"}
+	for _, r := range regions {
+		assert.NoError(t, c.Put(Key{Provider: "aws", Region: r}, cluster.GetMockClusters(2)))
+	}
+
+	entries, err = c.Entries()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, len(regions)) {
+		assert.Equal(t, "ap-south-1", entries[0].Key.Region)
+		assert.Equal(t, "us-east-1", entries[2].Key.Region)
+	}
+
+	count, err := c.Clear()
+	assert.NoError(t, err)
+	assert.Equal(t, len(regions), count)
+
+	entries, err = c.Entries()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -2,9 +2,9 @@
 package cluster
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
-	"html/template"
+	"time"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -18,6 +18,20 @@ const (
 	Azure
 )
 
+var providerNames = map[K8sProvider]string{
+	None:   "none",
+	AWS:    "aws",
+	Google: "google",
+	Azure:  "azure",
+}
+
+func (p K8sProvider) String() string {
+	if name, ok := providerNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("provider-%d", int(p))
+}
+
 // Cluster is the representation of a K8S Cluster
 // For now it is tailored to AWS, more specifically eks clusters
 type Cluster struct {
@@ -28,8 +42,24 @@ type Cluster struct {
 	Endpoint                 string
 	CertificateAuthorityData string
 	Status                   string
-	GenerateClusterConfig    func(cls *Cluster) *clientcmdapi.Cluster
-	GenerateAuthInfo         func(cls *Cluster) *clientcmdapi.AuthInfo
+	Profile                  string
+	RoleARN                  string
+	AccountID                string
+	AccountName              string
+	ARN                      string
+	KubernetesVersion        string
+	PlatformVersion          string
+	Tags                     map[string]string
+	CreatedAt                time.Time
+	EndpointPublicAccess     bool
+	EndpointPrivateAccess    bool
+	VpcID                    string
+	OIDCIssuer               string
+	// DiscoveredAt is when the cluster was described, it is older than
+	// the current run if the cluster was loaded from the cache
+	DiscoveredAt          time.Time
+	GenerateClusterConfig func(cls *Cluster) *clientcmdapi.Cluster  `json:"-"`
+	GenerateAuthInfo      func(cls *Cluster) *clientcmdapi.AuthInfo `json:"-"`
 }
 
 func NewCluster() *Cluster {
@@ -38,8 +68,25 @@ func NewCluster() *Cluster {
 	}
 }
 
+// UnmarshalJSON restores the default cluster config generator
+// because functions can't be serialized
+func (cls *Cluster) UnmarshalJSON(data []byte) error {
+	type plain Cluster
+	p := plain(*NewCluster())
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*cls = Cluster(p)
+	return nil
+}
+
+// UniqueIDPrefix starts all the unique IDs. The first releases did not
+// set the provider so the IDs keep the value of None, otherwise the
+// existing kubeconfig entries would be duplicated.
+const UniqueIDPrefix = "id-0-"
+
 func (cls *Cluster) GetUniqueID() string {
-	return fmt.Sprintf("id-%v-%v-%v-%v", cls.Provider, cls.ID, cls.Region, cls.Name)
+	return fmt.Sprintf("%v%v-%v-%v", UniqueIDPrefix, cls.ID, cls.Region, cls.Name)
 }
 
 func defaultGenerateClusterConfig(cls *Cluster) *clientcmdapi.Cluster {
@@ -61,6 +108,14 @@ func (cls *Cluster) GetName() string {
 	return cls.Name
 }
 
+func (cls *Cluster) GetProvider() string {
+	return cls.Provider.String()
+}
+
+func (cls *Cluster) GetDiscoveredAt() time.Time {
+	return cls.DiscoveredAt
+}
+
 func (cls *Cluster) GetRegion() string {
 	return cls.Region
 }
@@ -73,15 +128,48 @@ func (cls *Cluster) GetEndpoint() string {
 	return cls.Endpoint
 }
 
-func (cls *Cluster) PrettyName(templateValue string) (string, error) {
-	tmpl, err := template.New("context-name").Parse(templateValue)
-	if err != nil {
-		return "", err
-	}
-	var tpl bytes.Buffer
-	err = tmpl.Execute(&tpl, cls)
-	if err != nil {
-		return "", err
+func (cls *Cluster) GetAccountID() string {
+	return cls.AccountID
+}
+
+func (cls *Cluster) GetProfile() string {
+	return cls.Profile
+}
+
+func (cls *Cluster) GetTags() map[string]string {
+	return cls.Tags
+}
+
+func (cls *Cluster) GetKubernetesVersion() string {
+	return cls.KubernetesVersion
+}
+
+func (cls *Cluster) GetPlatformVersion() string {
+	return cls.PlatformVersion
+}
+
+func (cls *Cluster) GetCreatedAt() time.Time {
+	return cls.CreatedAt
+}
+
+func (cls *Cluster) GetVpcID() string {
+	return cls.VpcID
+}
+
+// GetEndpointAccess describes who can reach the API server endpoint
+func (cls *Cluster) GetEndpointAccess() string {
+	switch {
+	case cls.EndpointPublicAccess && cls.EndpointPrivateAccess:
+		return "public+private"
+	case cls.EndpointPublicAccess:
+		return "public"
+	case cls.EndpointPrivateAccess:
+		return "private"
 	}
-	return tpl.String(), nil
+	return ""
+}
+
+// PrettyName renders the context name of the cluster
+func (cls *Cluster) PrettyName(names *NameTemplate) (string, error) {
+	return names.Render(cls)
 }
@@ -0,0 +1,130 @@
+// Package internal provides function for working with EKS cluseters
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NameTemplate renders the context names of the clusters, the template
+// has access to the Cluster type and to the functions of nameFuncs
+type NameTemplate struct {
+	tmpl *template.Template
+	// accountAliases maps account IDs to the names used by accountAlias
+	accountAliases map[string]string
+}
+
+// sampleCluster is rendered when the template is parsed in order to
+// catch the invalid fields and functions before the discovery
+var sampleCluster = &Cluster{
+	Provider:    AWS,
+	Name:        "sample",
+	Region:      "us-east-1",
+	ID:          "arn:aws:eks:us-east-1:123456789012:cluster/sample",
+	AccountID:   "123456789012",
+	AccountName: "sample",
+	ARN:         "arn:aws:eks:us-east-1:123456789012:cluster/sample",
+	Tags:        map[string]string{},
+	CreatedAt:   time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func truncate(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 || len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
+
+func defaultValue(value, s string) string {
+	if s == "" {
+		return value
+	}
+	return s
+}
+
+func regexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+func regexFind(pattern, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(s), nil
+}
+
+// nameFuncs are the helpers of the template, the string is always the
+// last argument so they can be chained: {{.Name | trimPrefix "eks-" | upper}}
+// tag and accountAlias depend on the rendered cluster.
+func nameFuncs(cls *Cluster, accountAliases map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+		"replace":      func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"regexReplace": regexReplace,
+		"regexFind":    regexFind,
+		"trimPrefix":   func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix":   func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"truncate":     truncate,
+		"default":      defaultValue,
+		"tag":          func(key string) string { return cls.Tags[key] },
+		"accountAlias": func() string {
+			if name, ok := accountAliases[cls.AccountID]; ok {
+				return name
+			}
+			return defaultValue(cls.AccountID, cls.AccountName)
+		},
+	}
+}
+
+// ParseNameTemplate parses the template of the context names, the
+// account aliases are returned by accountAlias instead of the account
+// name
+func ParseNameTemplate(value string, accountAliases map[string]string) (*NameTemplate, error) {
+	tmpl, err := template.New("context-name").Funcs(nameFuncs(sampleCluster, accountAliases)).Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	t := &NameTemplate{tmpl: tmpl, accountAliases: accountAliases}
+	// the sample has no tags so an empty name is not an error here
+	if _, err := t.Execute(sampleCluster); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Execute renders the template, unlike Render an empty value is not an
+// error
+func (t *NameTemplate) Execute(cls *Cluster) (string, error) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	var name bytes.Buffer
+	if err := tmpl.Funcs(nameFuncs(cls, t.accountAliases)).Execute(&name, cls); err != nil {
+		return "", err
+	}
+	return name.String(), nil
+}
+
+// Render returns the context name of the cluster
+func (t *NameTemplate) Render(cls *Cluster) (string, error) {
+	name, err := t.Execute(cls)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty name for cluster %v", cls.Name)
+	}
+	return name, nil
+}
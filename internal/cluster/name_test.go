@@ -0,0 +1,82 @@
+// Package internal provides function for working with EKS cluseters
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newNamedCluster() *Cluster {
+	cls := NewCluster()
+	cls.Name = "eks-Prod+Blue&Green"
+	cls.Region = "eu-west-1"
+	cls.AccountID = "123456789012"
+	cls.AccountName = "production"
+	cls.Tags = map[string]string{"team": "payments"}
+	return cls
+}
+
+func TestNameTemplate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		Template string
+		Aliases  map[string]string
+		Name     string
+	}{
+		{"{{.Name}}", nil, "eks-Prod+Blue&Green"},
+		{"{{.Name | lower}}", nil, "eks-prod+blue&green"},
+		{"{{.Region | upper}}", nil, "EU-WEST-1"},
+		{`{{.Name | replace "&" "-and-"}}`, nil, "eks-Prod+Blue-and-Green"},
+		{`{{.Name | regexReplace "[^a-zA-Z0-9-]+" "-"}}`, nil, "eks-Prod-Blue-Green"},
+		{`{{.Name | regexFind "[A-Z][a-z]+"}}`, nil, "Prod"},
+		{`{{.Name | trimPrefix "eks-"}}`, nil, "Prod+Blue&Green"},
+		{`{{.Region | trimSuffix "-1"}}`, nil, "eu-west"},
+		{`{{.Name | truncate 8}}`, nil, "eks-Prod"},
+		{`{{.Name | truncate 100}}`, nil, "eks-Prod+Blue&Green"},
+		{`{{tag "team"}}-{{tag "env" | default "none"}}`, nil, "payments-none"},
+		{"{{accountAlias}}", nil, "production"},
+		{"{{accountAlias}}", map[string]string{"123456789012": "prod"}, "prod"},
+		{"{{accountAlias}}", map[string]string{"210987654321": "dev"}, "production"},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("render %v", tt.Template), func(t *testing.T) {
+			names, err := ParseNameTemplate(tt.Template, tt.Aliases)
+			if assert.NoError(t, err) {
+				name, err := names.Render(newNamedCluster())
+				assert.NoError(t, err)
+				assert.Equal(t, tt.Name, name)
+			}
+		})
+	}
+}
+
+func TestAccountAliasFallback(t *testing.T) {
+	t.Parallel()
+	names, err := ParseNameTemplate("{{accountAlias}}", nil)
+	assert.NoError(t, err)
+	cls := newNamedCluster()
+	cls.AccountName = ""
+	name, err := names.Render(cls)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456789012", name)
+}
+
+func TestInvalidNameTemplate(t *testing.T) {
+	t.Parallel()
+	for _, value := range []string{
+		"{{.Name", "{{.Missing}}", "{{unknown .Name}}", `{{.Name | regexFind "("}}`, `{{.Name | truncate "a"}}`,
+	} {
+		_, err := ParseNameTemplate(value, nil)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestEmptyName(t *testing.T) {
+	t.Parallel()
+	names, err := ParseNameTemplate(`{{tag "missing"}}`, nil)
+	assert.NoError(t, err)
+	_, err = names.Render(newNamedCluster())
+	assert.ErrorContains(t, err, "empty name")
+}
@@ -4,6 +4,7 @@ package cluster
 import (
 	"fmt"
 	"math/rand"
+	"time"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -39,6 +40,15 @@ func getMockClusters(i int, r string) *Cluster {
 	c.Status = fmt.Sprintf("clucster-status-%v-%v", r, i)
 	c.Endpoint = fmt.Sprintf("clucster-endpoint-%v-%v", r, i)
 	c.CertificateAuthorityData = fmt.Sprintf("clucster-certificate-authority-data-%v-%v", r, i)
+	c.ARN = c.ID
+	c.KubernetesVersion = fmt.Sprintf("1.%v", 25+i%8)
+	c.PlatformVersion = fmt.Sprintf("eks.%v", i%10)
+	c.Tags = map[string]string{"env": fmt.Sprintf("env-%v", i%3)}
+	c.CreatedAt = time.Date(2024, time.January, 1+i%28, 0, 0, 0, 0, time.UTC)
+	c.EndpointPublicAccess = i%2 == 0
+	c.EndpointPrivateAccess = true
+	c.VpcID = fmt.Sprintf("vpc-%v-%v", r, i)
+	c.OIDCIssuer = fmt.Sprintf("https://oidc.eks.amazonaws.com/id/%v%v", r, i)
 	c.GenerateClusterConfig = defaultGenerateClusterConfig
 	c.GenerateAuthInfo = dummyGenerateAuthInfo
 	return c
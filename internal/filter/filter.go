@@ -0,0 +1,283 @@
+// Package filter selects clusters with expressions like
+// `tag:env=prod AND (status=ACTIVE OR version>=1.28)`
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+)
+
+const tagPrefix = "tag:"
+
+// Filter decides if a cluster is selected
+type Filter interface {
+	Match(cls *cluster.Cluster) bool
+}
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	versionField
+	timeField
+)
+
+type field struct {
+	kind  fieldKind
+	value func(cls *cluster.Cluster) string
+}
+
+var fields = map[string]field{
+	"name":         {textField, func(cls *cluster.Cluster) string { return cls.Name }},
+	"region":       {textField, func(cls *cluster.Cluster) string { return cls.Region }},
+	"status":       {textField, func(cls *cluster.Cluster) string { return cls.Status }},
+	"arn":          {textField, func(cls *cluster.Cluster) string { return cls.ARN }},
+	"endpoint":     {textField, func(cls *cluster.Cluster) string { return cls.Endpoint }},
+	"account":      {textField, func(cls *cluster.Cluster) string { return cls.AccountID }},
+	"account-name": {textField, func(cls *cluster.Cluster) string { return cls.AccountName }},
+	"profile":      {textField, func(cls *cluster.Cluster) string { return cls.Profile }},
+	"role":         {textField, func(cls *cluster.Cluster) string { return cls.RoleARN }},
+	"vpc":          {textField, func(cls *cluster.Cluster) string { return cls.VpcID }},
+	"access":       {textField, func(cls *cluster.Cluster) string { return cls.GetEndpointAccess() }},
+	"version":      {versionField, func(cls *cluster.Cluster) string { return cls.KubernetesVersion }},
+	"platform":     {versionField, func(cls *cluster.Cluster) string { return cls.PlatformVersion }},
+	"created": {timeField, func(cls *cluster.Cluster) string {
+		if cls.CreatedAt.IsZero() {
+			return ""
+		}
+		return cls.CreatedAt.Format(time.RFC3339)
+	}},
+}
+
+// Fields returns the names of the fields that can be used in
+// expressions, besides tag:<key>
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupField(name string) (field, error) {
+	if key, ok := strings.CutPrefix(name, tagPrefix); ok {
+		if key == "" {
+			return field{}, fmt.Errorf("missing tag key in %q", name)
+		}
+		return field{textField, func(cls *cluster.Cluster) string { return cls.Tags[key] }}, nil
+	}
+	f, ok := fields[strings.ToLower(name)]
+	if !ok {
+		return field{}, fmt.Errorf("unknown field %q, use tag:<key> or one of %v", name, Fields())
+	}
+	return f, nil
+}
+
+type and []Filter
+
+func (a and) Match(cls *cluster.Cluster) bool {
+	for _, f := range a {
+		if !f.Match(cls) {
+			return false
+		}
+	}
+	return true
+}
+
+type or []Filter
+
+func (o or) Match(cls *cluster.Cluster) bool {
+	for _, f := range o {
+		if f.Match(cls) {
+			return true
+		}
+	}
+	return false
+}
+
+type not struct {
+	Filter
+}
+
+func (n not) Match(cls *cluster.Cluster) bool {
+	return !n.Filter.Match(cls)
+}
+
+// comparison matches a field against a value with one operator
+type comparison struct {
+	field field
+	op    string
+	value string
+
+	regex   *regexp.Regexp
+	version []int
+	time    time.Time
+}
+
+func newComparison(name, op, value string) (*comparison, error) {
+	f, err := lookupField(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &comparison{field: f, op: op, value: value}
+
+	switch op {
+	case "~", "!~":
+		if c.regex, err = regexp.Compile(value); err != nil {
+			return nil, fmt.Errorf("invalid regular expression for %v: %w", name, err)
+		}
+		return c, nil
+	case "=", "!=":
+	default:
+		if f.kind == textField {
+			return nil, fmt.Errorf("field %v can't be compared with %v", name, op)
+		}
+	}
+
+	switch f.kind {
+	case versionField:
+		if c.version, err = parseVersion(value); err != nil {
+			return nil, err
+		}
+	case timeField:
+		if c.time, err = parseTime(value); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *comparison) Match(cls *cluster.Cluster) bool {
+	actual := c.field.value(cls)
+	switch c.op {
+	case "~":
+		return c.regex.MatchString(actual)
+	case "!~":
+		return !c.regex.MatchString(actual)
+	}
+
+	order, ok := c.compare(actual)
+	if !ok {
+		// a missing or invalid value only matches the negation
+		return c.op == "!="
+	}
+	switch c.op {
+	case "=":
+		return order == 0
+	case "!=":
+		return order != 0
+	case ">=":
+		return order >= 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case "<":
+		return order < 0
+	}
+	return false
+}
+
+// compare returns the order of the actual value relative to the
+// expected one, false if the actual value can't be compared
+func (c *comparison) compare(actual string) (int, bool) {
+	switch c.field.kind {
+	case versionField:
+		v, err := parseVersion(actual)
+		if err != nil {
+			return 0, false
+		}
+		return compareVersions(v, c.version), true
+	case timeField:
+		t, err := parseTime(actual)
+		if err != nil {
+			return 0, false
+		}
+		return t.Compare(c.time), true
+	}
+	return strings.Compare(actual, c.value), true
+}
+
+// parseVersion accepts versions like 1.28, v1.28.3 or eks.5
+func parseVersion(value string) ([]int, error) {
+	trimmed := strings.TrimLeft(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.")
+	if trimmed == "" {
+		return nil, fmt.Errorf("invalid version %q", value)
+	}
+	parts := strings.Split(trimmed, ".")
+	version := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", value)
+		}
+		version = append(version, n)
+	}
+	return version, nil
+}
+
+// compareVersions compares segment by segment, missing segments are 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// CompareVersions orders two version strings like 1.28 or eks.5,
+// invalid versions are ordered before the valid ones
+func CompareVersions(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return compareVersions(va, vb)
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD or RFC3339", value)
+}
+
+// Apply returns the clusters selected by the filter
+func Apply(f Filter, clusters []*cluster.Cluster) []*cluster.Cluster {
+	if f == nil {
+		return clusters
+	}
+	selected := make([]*cluster.Cluster, 0, len(clusters))
+	for _, cls := range clusters {
+		if f.Match(cls) {
+			selected = append(selected, cls)
+		}
+	}
+	return selected
+}
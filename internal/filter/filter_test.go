@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCluster() *cluster.Cluster {
+	cls := cluster.NewCluster()
+	cls.Name = "payments-prod"
+	cls.Region = "eu-west-1"
+	cls.Status = "ACTIVE"
+	cls.KubernetesVersion = "1.29"
+	cls.PlatformVersion = "eks.7"
+	cls.Tags = map[string]string{"env": "prod", "team": "payments"}
+	cls.CreatedAt = time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	cls.EndpointPrivateAccess = true
+	return cls
+}
+
+func TestParseMatch(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		Expr  string
+		Match bool
+	}{
+		{"tag:env=prod", true},
+		{"tag:env=dev", false},
+		{"tag:env!=dev", true},
+		{"tag:missing!=x", true},
+		{"tag:missing=x", false},
+		{"status=ACTIVE", true},
+		{"STATUS=ACTIVE", true},
+		{"name~^payments-", true},
+		{"name!~^payments-", false},
+		{"name~'^payments-(prod|dev)$'", true},
+		{"version>=1.28", true},
+		{"version>=1.30", false},
+		{"version=1.29.0", true},
+		{"version<v1.30", true},
+		{"version>1.29", false},
+		{"platform>eks.5", true},
+		{"created>=2024-01-01", true},
+		{"created<2024-03-10", false},
+		{"access=private", true},
+		{"region=eu-west-1 AND status=ACTIVE", true},
+		{"region=us-east-1 AND status=ACTIVE", false},
+		{"region=us-east-1 OR status=ACTIVE", true},
+		{"region=us-east-1 or status=DELETING", false},
+		{"region=us-east-1 AND status=DELETING OR tag:team=payments", true},
+		{"region=us-east-1 AND (status=DELETING OR tag:team=payments)", false},
+		{"NOT region=us-east-1", true},
+		{"NOT (region=eu-west-1 AND tag:env=prod)", false},
+		{`name="payments-prod"`, true},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%v matches %v", tt.Expr, tt.Match)
+		t.Run(testname, func(t *testing.T) {
+			f, err := Parse(tt.Expr)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.Match, f.Match(newTestCluster()))
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+	tts := []string{
+		"",
+		"name",
+		"unknown=x",
+		"tag:=x",
+		"name>x",
+		"version>=latest",
+		"created>yesterday",
+		"name~[",
+		"(name=x",
+		"name=x)",
+		"name=x AND",
+		"name='x",
+		"name=x region=y",
+	}
+
+	for _, expr := range tts {
+		testname := fmt.Sprintf("%q is invalid", expr)
+		t.Run(testname, func(t *testing.T) {
+			_, err := Parse(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseAllApply(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetMockClusters(6)
+
+	f, err := ParseAll(nil)
+	assert.NoError(t, err)
+	assert.Len(t, Apply(f, clusters), len(clusters))
+
+	f, err = ParseAll([]string{"tag:env=env-0", "access=public+private"})
+	assert.NoError(t, err)
+	selected := Apply(f, clusters)
+	for _, cls := range selected {
+		assert.Equal(t, "env-0", cls.Tags["env"])
+		assert.True(t, cls.EndpointPublicAccess)
+	}
+	assert.Len(t, selected, 1)
+
+	_, err = ParseAll([]string{"name=x", "bogus"})
+	assert.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	t.Parallel()
+	tts := []struct {
+		A, B     string
+		Expected int
+	}{
+		{"1.28", "1.28", 0},
+		{"1.28", "1.28.0", 0},
+		{"1.9", "1.28", -1},
+		{"1.30", "1.28", 1},
+		{"eks.10", "eks.9", 1},
+		{"", "1.28", -1},
+		{"1.28", "unknown", 1},
+	}
+
+	for _, tt := range tts {
+		testname := fmt.Sprintf("%v vs %v", tt.A, tt.B)
+		t.Run(testname, func(t *testing.T) {
+			assert.Equal(t, tt.Expected, CompareVersions(tt.A, tt.B))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// operators ordered so the longest ones are matched first
+var operators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+const operatorChars = "=!~<>"
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// Parse builds a filter from an expression made of comparisons like
+// `field=value` combined with AND, OR, NOT and parentheses. AND binds
+// stronger than OR. Values with spaces or parentheses must be quoted.
+func Parse(expr string) (Filter, error) {
+	p := &parser{input: expr}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return f, nil
+}
+
+// ParseAll parses every expression, a cluster is selected only if it
+// matches all of them. Without expressions nil is returned.
+func ParseAll(exprs []string) (Filter, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	filters := make(and, 0, len(exprs))
+	for _, expr := range exprs {
+		f, err := Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %v: %v", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// keyword consumes the keyword if it is the next word
+func (p *parser) keyword(word string) bool {
+	p.skipSpaces()
+	end := p.pos + len(word)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], word) {
+		return false
+	}
+	if end < len(p.input) {
+		next := rune(p.input[end])
+		if !unicode.IsSpace(next) && next != '(' {
+			return false
+		}
+	}
+	p.pos = end
+	return true
+}
+
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := or{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := and{left}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	if p.keyword("NOT") {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{f}, nil
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return f, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Filter, error) {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(operatorChars, rune(p.input[p.pos])) &&
+		!unicode.IsSpace(rune(p.input[p.pos])) && p.input[p.pos] != '(' && p.input[p.pos] != ')' {
+		p.pos++
+	}
+	name := p.input[start:p.pos]
+	if name == "" {
+		return nil, p.errorf("expected a field")
+	}
+
+	op := ""
+	for _, candidate := range operators {
+		if strings.HasPrefix(p.input[p.pos:], candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, p.errorf("expected an operator after %q, one of %v", name, operators)
+	}
+	p.pos += len(op)
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newComparison(name, op, value)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return c, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		quote := p.input[p.pos]
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("unterminated quoted value")
+		}
+		value := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !unicode.IsSpace(rune(p.input[p.pos])) && p.input[p.pos] != ')' {
+		p.pos++
+	}
+	return p.input[start:p.pos], nil
+}
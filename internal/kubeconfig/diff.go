@@ -0,0 +1,101 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	diffContextLines = 3
+
+	KindCluster = "cluster"
+	KindUser    = "user"
+	KindContext = "context"
+
+	ActionAdded   = "added"
+	ActionChanged = "changed"
+	ActionRemoved = "removed"
+)
+
+// Change is a cluster, user or context that differs between two kubeconfigs
+type Change struct {
+	Kind   string
+	Name   string
+	Action string
+}
+
+// DeepCopy returns an independent copy, used to compare the kubeconfig
+// before and after the changes
+func (k *Kubeconfig) DeepCopy() *Kubeconfig {
+	return &Kubeconfig{cfg: k.cfg.DeepCopy(), Version: k.Version}
+}
+
+// normalize writes and reads back the config so the values loaded from
+// a file and the ones built in memory can be compared
+func (k *Kubeconfig) normalize() (*clientcmdapi.Config, []byte, error) {
+	data, err := clientcmd.Write(*k.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, data, nil
+}
+
+func compareMaps[T any](kind string, before, after map[string]T) []Change {
+	changes := []Change{}
+	for name, b := range before {
+		a, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionRemoved})
+		case !reflect.DeepEqual(a, b):
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionChanged})
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionAdded})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// Diff returns the changed clusters, users and contexts together with
+// a unified diff of the two kubeconfigs
+func Diff(before, after *Kubeconfig, beforeName, afterName string) ([]Change, string, error) {
+	b, bData, err := before.normalize()
+	if err != nil {
+		return nil, "", err
+	}
+	a, aData, err := after.normalize()
+	if err != nil {
+		return nil, "", err
+	}
+
+	changes := compareMaps(KindCluster, b.Clusters, a.Clusters)
+	changes = append(changes, compareMaps(KindUser, b.AuthInfos, a.AuthInfos)...)
+	changes = append(changes, compareMaps(KindContext, b.Contexts, a.Contexts)...)
+
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(bData)),
+		B:        difflib.SplitLines(string(aData)),
+		FromFile: beforeName,
+		ToFile:   afterName,
+		Context:  diffContextLines,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return changes, text, nil
+}
@@ -0,0 +1,94 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	cluster "github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffNoChanges(t *testing.T) {
+	t.Parallel()
+	k := New()
+	for _, cls := range cluster.GetPredictableMockClusters(3) {
+		k.AddCluster(cls, cls.Name)
+	}
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NoError(t, k.Persist(path))
+
+	loaded, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	changes, diff, err := Diff(loaded, k, "a", "b")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Empty(t, diff)
+}
+
+func TestDiffRediscovered(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetPredictableMockClusters(2)
+	k := New()
+	for _, cls := range clusters {
+		k.AddEntry(cls, EntryNames{User: "shared", Context: cls.Name, SharedUser: true})
+	}
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NoError(t, k.Persist(path))
+
+	loaded, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	updated := loaded.DeepCopy()
+	for _, cls := range clusters {
+		// a later discovery of the same clusters
+		cls.DiscoveredAt = cls.DiscoveredAt.Add(time.Hour)
+		updated.AddEntry(cls, EntryNames{User: "shared", Context: cls.Name, SharedUser: true})
+	}
+	changes, diff, err := Diff(loaded, updated, "a", "b")
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Empty(t, diff)
+
+	clusters[0].Endpoint = "https://new-endpoint"
+	updated.AddEntry(clusters[0], EntryNames{User: "shared", Context: clusters[0].Name, SharedUser: true})
+	changes, _, err = Diff(loaded, updated, "a", "b")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Change{
+		{KindCluster, clusters[0].GetUniqueID(), ActionChanged},
+		{KindUser, "shared", ActionChanged},
+		{KindContext, clusters[0].Name, ActionChanged},
+	}, changes)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetPredictableMockClusters(3)
+	before := New()
+	before.AddCluster(clusters[0], "removed")
+	before.AddCluster(clusters[1], "changed")
+
+	after := before.DeepCopy()
+	after.Remove(after.Entries(clusters[0].GetUniqueID())[0])
+	clusters[1].Endpoint = "https://new-endpoint"
+	after.AddCluster(clusters[1], "changed")
+	after.AddCluster(clusters[2], "added")
+
+	changes, diff, err := Diff(before, after, "before", "after")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Change{
+		{KindCluster, clusters[0].GetUniqueID(), ActionRemoved},
+		{KindUser, clusters[0].GetUniqueID(), ActionRemoved},
+		{KindContext, "removed", ActionRemoved},
+		{KindCluster, clusters[1].GetUniqueID(), ActionChanged},
+		{KindCluster, clusters[2].GetUniqueID(), ActionAdded},
+		{KindUser, clusters[2].GetUniqueID(), ActionAdded},
+		{KindContext, "added", ActionAdded},
+	}, changes)
+	assert.Contains(t, diff, "--- before")
+	assert.Contains(t, diff, "+++ after")
+	assert.Contains(t, diff, "+    server: https://new-endpoint")
+
+	// the original is not changed by the copy
+	assert.Len(t, before.Entries(""), 2)
+}
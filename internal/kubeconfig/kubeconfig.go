@@ -3,8 +3,13 @@ package kubeconfig
 
 import (
 	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	cluster "github.com/mateimicu/kdiscover/internal/cluster"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -13,16 +18,16 @@ type ClusterExporter interface {
 	GetConfigCluster() *clientcmdapi.Cluster
 	GetConfigAuthInfo() *clientcmdapi.AuthInfo
 	GetUniqueID() string
-}
-
-// GetDefaultKubeconfigPath Returns the default path for the kubeconfig file
-// based on the system
-func GetDefaultKubeconfigPath() string {
-	return clientcmd.RecommendedHomeFile
+	GetProvider() string
+	GetAccountID() string
+	GetRegion() string
+	GetDiscoveredAt() time.Time
 }
 
 type Kubeconfig struct {
 	cfg *clientcmdapi.Config
+	// Version of kdiscover recorded in the markers of the added entries
+	Version string
 }
 
 func LoadKubeconfig(kubeconfigpath string) (*Kubeconfig, error) {
@@ -42,23 +47,270 @@ func New() *Kubeconfig {
 	}
 }
 
-// Persist the kubeconfig to the disk
-func (k *Kubeconfig) Persist(path string) error {
-	return clientcmd.WriteToFile(*k.cfg, path)
+// EntryNames are the names of the cluster, the user and the context
+// written for a cluster, the cluster and the user default to the unique
+// ID of the cluster
+type EntryNames struct {
+	Cluster string
+	User    string
+	Context string
+	// SharedUser is set if the user is written for several clusters, its
+	// marker only records the provider and the version
+	SharedUser bool
 }
 
+// AddCluster writes the cluster, the user and the context of the
+// cluster, all of them marked as written by kdiscover
 func (k *Kubeconfig) AddCluster(cls ClusterExporter, ctxName string) {
-	key := cls.GetUniqueID()
-	k.cfg.AuthInfos[key] = cls.GetConfigAuthInfo()
-	k.cfg.Clusters[key] = cls.GetConfigCluster()
-	k.cfg.Contexts[ctxName] = getConfigContext(key)
+	k.AddEntry(cls, EntryNames{Context: ctxName})
 }
 
-func getConfigContext(ctxName string) *clientcmdapi.Context {
+// AddEntry is AddCluster with the names of the cluster and the user
+func (k *Kubeconfig) AddEntry(cls ClusterExporter, names EntryNames) {
+	key := cls.GetUniqueID()
+	if names.Cluster == "" {
+		names.Cluster = key
+	}
+	if names.User == "" {
+		names.User = key
+	}
+	marker := Marker{
+		Provider:     cls.GetProvider(),
+		ID:           key,
+		AccountID:    cls.GetAccountID(),
+		Region:       cls.GetRegion(),
+		DiscoveredAt: cls.GetDiscoveredAt(),
+		Version:      k.Version,
+	}
+
+	k.moveEntries(key, names)
+
+	authInfo := cls.GetConfigAuthInfo()
+	c := cls.GetConfigCluster()
 	ctx := clientcmdapi.NewContext()
-	ctx.Cluster = ctxName
-	ctx.AuthInfo = ctxName
-	return ctx
+	ctx.Cluster = names.Cluster
+	ctx.AuthInfo = names.User
+
+	userMarker := marker
+	if names.SharedUser {
+		userMarker = Marker{Provider: marker.Provider, DiscoveredAt: marker.DiscoveredAt, Version: marker.Version}
+	}
+	clusterMarker, ctxMarker := marker, marker
+	if k.unchanged(names, c, authInfo, ctx) {
+		clusterMarker = clusterMarker.keepDiscoveredAt(k.cfg.Clusters[names.Cluster].Extensions)
+		userMarker = userMarker.keepDiscoveredAt(k.cfg.AuthInfos[names.User].Extensions)
+		ctxMarker = ctxMarker.keepDiscoveredAt(k.cfg.Contexts[names.Context].Extensions)
+	}
+
+	authInfo.Extensions = userMarker.setOn(authInfo.Extensions)
+	k.cfg.AuthInfos[names.User] = authInfo
+	c.Extensions = clusterMarker.setOn(c.Extensions)
+	k.cfg.Clusters[names.Cluster] = c
+	ctx.Extensions = ctxMarker.setOn(ctx.Extensions)
+	k.cfg.Contexts[names.Context] = ctx
+}
+
+// unchanged tells if the cluster, the user and the context stored under
+// the names are the same as the given ones, ignoring the extensions
+func (k *Kubeconfig) unchanged(
+	names EntryNames, c *clientcmdapi.Cluster, authInfo *clientcmdapi.AuthInfo, ctx *clientcmdapi.Context,
+) bool {
+	oldCluster, okCluster := k.cfg.Clusters[names.Cluster]
+	oldUser, okUser := k.cfg.AuthInfos[names.User]
+	oldCtx, okCtx := k.cfg.Contexts[names.Context]
+	if !okCluster || !okUser || !okCtx {
+		return false
+	}
+
+	entryConfig := func(c *clientcmdapi.Cluster, u *clientcmdapi.AuthInfo, ctx *clientcmdapi.Context) *Kubeconfig {
+		cfg := clientcmdapi.NewConfig()
+		cfg.Clusters[names.Cluster], cfg.AuthInfos[names.User], cfg.Contexts[names.Context] =
+			c.DeepCopy(), u.DeepCopy(), ctx.DeepCopy()
+		cfg.Clusters[names.Cluster].Extensions = nil
+		cfg.AuthInfos[names.User].Extensions = nil
+		cfg.Contexts[names.Context].Extensions = nil
+		return &Kubeconfig{cfg: cfg}
+	}
+	before, _, err := entryConfig(oldCluster, oldUser, oldCtx).normalize()
+	if err != nil {
+		return false
+	}
+	after, _, err := entryConfig(c, authInfo, ctx).normalize()
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(before, after)
+}
+
+// moveEntries moves the contexts of the entries of the cluster written
+// under other names, by an older release or with other templates
+func (k *Kubeconfig) moveEntries(id string, names EntryNames) {
+	moved := k.entries(func(key string, m *Marker) bool {
+		return key != names.Cluster && Entry{Key: key, Marker: m}.ID() == id
+	})
+	for _, entry := range moved {
+		for _, ctx := range k.cfg.Contexts {
+			if ctx.Cluster != entry.Key {
+				continue
+			}
+			ctx.Cluster = names.Cluster
+			if ctx.AuthInfo == entry.User {
+				ctx.AuthInfo = names.User
+			}
+		}
+		// nothing uses them anymore, unless the user is shared
+		k.Remove(entry)
+	}
+}
+
+// Entry is a cluster together with the contexts using it and their user,
+// like the ones added by AddCluster
+type Entry struct {
+	// Key is the name of the cluster
+	Key      string
+	Contexts []string
+	Cluster  *clientcmdapi.Cluster
+	// User is the user of the context written by kdiscover, or the user
+	// with the same name as the cluster if there are no contexts
+	User     string
+	AuthInfo *clientcmdapi.AuthInfo
+	// Marker is nil if the cluster was not written by kdiscover or by
+	// a release without markers
+	Marker *Marker
+}
+
+// ID is the unique ID of the cluster recorded in the marker, the entries
+// written without markers used it as key
+func (e Entry) ID() string {
+	if e.Marker != nil && e.Marker.ID != "" {
+		return e.Marker.ID
+	}
+	return e.Key
+}
+
+// Entries returns the entries with keys starting with the prefix,
+// sorted by key
+func (k *Kubeconfig) Entries(prefix string) []Entry {
+	return k.entries(func(key string, _ *Marker) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// MarkedEntries returns the entries written by kdiscover with markers
+// matching the query, sorted by key
+func (k *Kubeconfig) MarkedEntries(q MarkerQuery) []Entry {
+	return k.entries(func(_ string, m *Marker) bool {
+		return q.Matches(m)
+	})
+}
+
+func (k *Kubeconfig) entries(selected func(key string, m *Marker) bool) []Entry {
+	entries := []Entry{}
+	for key, c := range k.cfg.Clusters {
+		marker := getMarker(c.Extensions)
+		if !selected(key, marker) {
+			continue
+		}
+		entry := Entry{Key: key, Contexts: []string{}, Cluster: c, User: key, Marker: marker}
+		for name, ctx := range k.cfg.Contexts {
+			if ctx.Cluster == key {
+				entry.Contexts = append(entry.Contexts, name)
+			}
+		}
+		sort.Strings(entry.Contexts)
+		entry.User = k.entryUser(key, entry.Contexts)
+		entry.AuthInfo = k.cfg.AuthInfos[entry.User]
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// entryUser is the user of the first context written by kdiscover, or
+// of the first context if none of them is marked
+func (k *Kubeconfig) entryUser(key string, contexts []string) string {
+	if len(contexts) == 0 {
+		return key
+	}
+	for _, name := range contexts {
+		if ctx := k.cfg.Contexts[name]; getMarker(ctx.Extensions) != nil {
+			return ctx.AuthInfo
+		}
+	}
+	return k.cfg.Contexts[contexts[0]].AuthInfo
+}
+
+// Remove deletes the cluster, the contexts and the users of the entry,
+// the users still used by other contexts are kept. The current context
+// is unset if it was one of them.
+func (k *Kubeconfig) Remove(entry Entry) {
+	delete(k.cfg.Clusters, entry.Key)
+	users := map[string]bool{entry.User: true}
+	for name, ctx := range k.cfg.Contexts {
+		if ctx.Cluster != entry.Key {
+			continue
+		}
+		users[ctx.AuthInfo] = true
+		delete(k.cfg.Contexts, name)
+		if k.cfg.CurrentContext == name {
+			k.cfg.CurrentContext = ""
+		}
+	}
+	for _, ctx := range k.cfg.Contexts {
+		delete(users, ctx.AuthInfo)
+	}
+	for user := range users {
+		delete(k.cfg.AuthInfos, user)
+	}
+}
+
+// ownerID is the unique ID in the marker, or the name of the entry if
+// it was not written by kdiscover
+func ownerID(name string, extensions map[string]runtime.Object) string {
+	if m := getMarker(extensions); m != nil && m.ID != "" {
+		return m.ID
+	}
+	return name
+}
+
+// ContextOwner returns the ID of the cluster of the context, the unique
+// ID for the contexts written by kdiscover, otherwise the cluster name
+func (k *Kubeconfig) ContextOwner(name string) (string, bool) {
+	ctx, ok := k.cfg.Contexts[name]
+	if !ok {
+		return "", false
+	}
+	return ownerID(ctx.Cluster, ctx.Extensions), true
+}
+
+// ClusterOwner returns the ID of the cluster stored under the name, see
+// ContextOwner
+func (k *Kubeconfig) ClusterOwner(name string) (string, bool) {
+	c, ok := k.cfg.Clusters[name]
+	if !ok {
+		return "", false
+	}
+	return ownerID(name, c.Extensions), true
+}
+
+// UserOwner returns the ID of the cluster of the user, see ContextOwner.
+// The shared users have an empty ID.
+func (k *Kubeconfig) UserOwner(name string) (string, bool) {
+	u, ok := k.cfg.AuthInfos[name]
+	if !ok {
+		return "", false
+	}
+	if m := getMarker(u.Extensions); m != nil {
+		return m.ID, true
+	}
+	return name, true
+}
+
+// SetCurrentContext selects the context used by kubectl
+func (k *Kubeconfig) SetCurrentContext(name string) {
+	k.cfg.CurrentContext = name
 }
 
 // Return all the clusters from a kubeconfig file
@@ -9,6 +9,7 @@ import (
 
 	cluster "github.com/mateimicu/kdiscover/internal/cluster"
 	"github.com/stretchr/testify/assert"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 var update = flag.Bool("update", false, "update .golden files")
@@ -72,3 +73,96 @@ func TestLoadKubeconfig(t *testing.T) {
 		})
 	}
 }
+
+func TestEntries(t *testing.T) {
+	t.Parallel()
+	k := New()
+	clusters := cluster.GetPredictableMockClusters(3)
+	for _, c := range clusters {
+		k.AddCluster(c, "ctx-"+c.Name)
+	}
+	k.cfg.Contexts["other"] = &clientcmdapi.Context{Cluster: clusters[0].GetUniqueID(), AuthInfo: "other"}
+	k.cfg.Clusters["manual"] = clusters[0].GetConfigCluster()
+
+	entries := k.Entries("id-")
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, clusters[0].GetUniqueID(), entries[0].Key)
+		assert.Equal(t, []string{"ctx-" + clusters[0].Name, "other"}, entries[0].Contexts)
+		assert.NotNil(t, entries[0].Cluster)
+		assert.NotNil(t, entries[0].AuthInfo)
+	}
+	assert.Len(t, k.Entries(""), 4)
+	assert.Empty(t, k.Entries("missing"))
+}
+
+func TestRemove(t *testing.T) {
+	t.Parallel()
+	k := New()
+	clusters := cluster.GetPredictableMockClusters(2)
+	for _, c := range clusters {
+		k.AddCluster(c, "ctx-"+c.Name)
+	}
+	k.cfg.CurrentContext = "ctx-" + clusters[0].Name
+
+	k.Remove(k.Entries(clusters[0].GetUniqueID())[0])
+
+	assert.NotContains(t, k.cfg.Clusters, clusters[0].GetUniqueID())
+	assert.NotContains(t, k.cfg.AuthInfos, clusters[0].GetUniqueID())
+	assert.NotContains(t, k.cfg.Contexts, "ctx-"+clusters[0].Name)
+	assert.Empty(t, k.cfg.CurrentContext)
+	assert.True(t, k.IsExported(clusters[1]))
+}
+
+func TestAddEntry(t *testing.T) {
+	t.Parallel()
+	k := New()
+	clusters := cluster.GetPredictableMockClusters(2)
+	for _, c := range clusters {
+		k.AddEntry(c, EntryNames{Cluster: "cluster-" + c.Name, User: "shared", Context: c.Name, SharedUser: true})
+	}
+
+	entries := k.Entries("cluster-")
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "shared", entries[0].User)
+		assert.NotNil(t, entries[0].AuthInfo)
+		assert.Equal(t, clusters[0].GetUniqueID(), entries[0].ID())
+	}
+	assert.Len(t, k.cfg.AuthInfos, 1)
+	owner, ok := k.UserOwner("shared")
+	assert.True(t, ok)
+	assert.Empty(t, owner)
+	owner, ok = k.ClusterOwner("cluster-" + clusters[1].Name)
+	assert.True(t, ok)
+	assert.Equal(t, clusters[1].GetUniqueID(), owner)
+	owner, ok = k.ContextOwner(clusters[1].Name)
+	assert.True(t, ok)
+	assert.Equal(t, clusters[1].GetUniqueID(), owner)
+	_, ok = k.ContextOwner("missing")
+	assert.False(t, ok)
+
+	// the shared user is removed with its last cluster
+	k.Remove(entries[0])
+	assert.Contains(t, k.cfg.AuthInfos, "shared")
+	k.Remove(entries[1])
+	assert.Empty(t, k.cfg.AuthInfos)
+}
+
+func TestAddEntryMovesContexts(t *testing.T) {
+	t.Parallel()
+	k := New()
+	cls := cluster.GetPredictableMockClusters(1)[0]
+	k.AddCluster(cls, "ctx")
+	k.cfg.Contexts["admin"] = &clientcmdapi.Context{Cluster: cls.GetUniqueID(), AuthInfo: "admin"}
+	k.cfg.AuthInfos["admin"] = clientcmdapi.NewAuthInfo()
+
+	k.AddEntry(cls, EntryNames{Cluster: "named", User: "named-user", Context: "ctx"})
+
+	assert.NotContains(t, k.cfg.Clusters, cls.GetUniqueID())
+	assert.NotContains(t, k.cfg.AuthInfos, cls.GetUniqueID())
+	assert.Equal(t, &clientcmdapi.Context{Cluster: "named", AuthInfo: "admin"}, k.cfg.Contexts["admin"])
+	entries := k.Entries("")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "named", entries[0].Key)
+		assert.Equal(t, []string{"admin", "ctx"}, entries[0].Contexts)
+	}
+}
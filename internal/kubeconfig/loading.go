@@ -0,0 +1,51 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// DestinationFirst writes to the first existing file of $KUBECONFIG,
+	// the same file kubectl uses for new entries
+	DestinationFirst = "first"
+	// DestinationDedicated writes to a file used only by kdiscover
+	DestinationDedicated = "dedicated"
+
+	dedicatedFileName = "kdiscover.yaml"
+)
+
+// Paths returns the kubeconfig files in the order client-go merges them,
+// an explicit path replaces the files of $KUBECONFIG
+func Paths(explicitPath string) []string {
+	if explicitPath != "" {
+		return []string{explicitPath}
+	}
+	return clientcmd.NewDefaultClientConfigLoadingRules().GetLoadingPrecedence()
+}
+
+// Destination returns the file the new entries are written to, it is
+// DestinationFirst, DestinationDedicated or a path
+func Destination(destination string, paths []string) string {
+	switch destination {
+	case DestinationFirst:
+		rules := &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+		return rules.GetDefaultFilename()
+	case DestinationDedicated:
+		return filepath.Join(clientcmd.RecommendedConfigDir, dedicatedFileName)
+	}
+	return destination
+}
+
+// LoadMerged loads the merged view of the files, like kubectl does, the
+// missing files are skipped. The result is only meant to be read.
+func LoadMerged(paths []string) (*Kubeconfig, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+	cfg, err := rules.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Kubeconfig{cfg: cfg}, nil
+}
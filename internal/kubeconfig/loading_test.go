@@ -0,0 +1,65 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cluster "github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestPaths(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar,
+		strings.Join([]string{first, second, first}, string(os.PathListSeparator)))
+
+	assert.Equal(t, []string{first, second}, Paths(""))
+	assert.Equal(t, []string{second}, Paths(second))
+
+	t.Setenv(clientcmd.RecommendedConfigPathEnvVar, "")
+	assert.Equal(t, []string{clientcmd.RecommendedHomeFile}, Paths(""))
+}
+
+func TestDestination(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	paths := []string{first, second}
+
+	// none of them exists
+	assert.Equal(t, first, Destination(DestinationFirst, paths))
+	assert.NoError(t, New().Persist(second))
+	assert.Equal(t, second, Destination(DestinationFirst, paths))
+
+	assert.Equal(t,
+		filepath.Join(clientcmd.RecommendedConfigDir, "kdiscover.yaml"), Destination(DestinationDedicated, paths))
+	assert.Equal(t, "/tmp/config", Destination("/tmp/config", paths))
+}
+
+func TestLoadMerged(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	clusters := cluster.GetPredictableMockClusters(2)
+
+	k := New()
+	k.AddCluster(clusters[0], "first")
+	assert.NoError(t, k.Persist(first))
+	k = New()
+	k.AddCluster(clusters[1], "second")
+	assert.NoError(t, k.Persist(second))
+
+	merged, err := LoadMerged([]string{first, filepath.Join(dir, "missing"), second})
+	assert.NoError(t, err)
+	assert.True(t, merged.IsExported(clusters[0]))
+	assert.True(t, merged.IsExported(clusters[1]))
+
+	assert.NoError(t, os.WriteFile(second, []byte("{"), 0o600))
+	_, err = LoadMerged([]string{first, second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MarkerExtension is the name of the extension added to the clusters,
+// users and contexts written by kdiscover
+const MarkerExtension = "kdiscover"
+
+// Marker records that an entry was written by kdiscover
+type Marker struct {
+	Provider     string    `json:"provider"`
+	ID           string    `json:"id"`
+	AccountID    string    `json:"accountID,omitempty"`
+	Region       string    `json:"region,omitempty"`
+	DiscoveredAt time.Time `json:"discoveredAt"`
+	Version      string    `json:"version,omitempty"`
+}
+
+// setOn adds the marker to the extensions, it is skipped if it can't be
+// encoded because the entry is still usable without it
+func (m Marker) setOn(extensions map[string]runtime.Object) map[string]runtime.Object {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return extensions
+	}
+	if extensions == nil {
+		extensions = map[string]runtime.Object{}
+	}
+	extensions[MarkerExtension] = &runtime.Unknown{Raw: data, ContentType: runtime.ContentTypeJSON}
+	return extensions
+}
+
+// keepDiscoveredAt returns the marker with the discovery time of the
+// existing marker if nothing else changed, so the entries of the
+// clusters already written are not rewritten by every update
+func (m Marker) keepDiscoveredAt(existing map[string]runtime.Object) Marker {
+	old := getMarker(existing)
+	if old == nil {
+		return m
+	}
+	kept := m
+	kept.DiscoveredAt = old.DiscoveredAt
+	if kept != *old {
+		return m
+	}
+	return kept
+}
+
+// getMarker reads the marker from the extensions, the ones loaded from
+// a file are always runtime.Unknown
+func getMarker(extensions map[string]runtime.Object) *Marker {
+	ext, ok := extensions[MarkerExtension].(*runtime.Unknown)
+	if !ok {
+		return nil
+	}
+	m := &Marker{}
+	if err := json.Unmarshal(ext.Raw, m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// MarkerQuery selects entries by their marker, the empty fields match
+// any value
+type MarkerQuery struct {
+	Provider  string
+	ID        string
+	AccountID string
+	Region    string
+}
+
+// Matches tells if the marker has all the fields of the query
+func (q MarkerQuery) Matches(m *Marker) bool {
+	if m == nil {
+		return false
+	}
+	for _, f := range [][2]string{
+		{q.Provider, m.Provider},
+		{q.ID, m.ID},
+		{q.AccountID, m.AccountID},
+		{q.Region, m.Region},
+	} {
+		if f[0] != "" && f[0] != f[1] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	cluster "github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddClusterMarkers(t *testing.T) {
+	t.Parallel()
+	cls := cluster.GetPredictableMockClusters(1)[0]
+	cls.Provider = cluster.AWS
+	cls.AccountID = "123456789012"
+	cls.DiscoveredAt = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	k := New()
+	k.Version = "v1.2.3"
+	k.AddCluster(cls, "ctx")
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.NoError(t, k.Persist(path))
+
+	loaded, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	expected := &Marker{
+		Provider:     "aws",
+		ID:           cls.GetUniqueID(),
+		AccountID:    "123456789012",
+		Region:       cls.Region,
+		DiscoveredAt: cls.DiscoveredAt,
+		Version:      "v1.2.3",
+	}
+	key := cls.GetUniqueID()
+	assert.Equal(t, expected, getMarker(loaded.cfg.Clusters[key].Extensions))
+	assert.Equal(t, expected, getMarker(loaded.cfg.AuthInfos[key].Extensions))
+	assert.Equal(t, expected, getMarker(loaded.cfg.Contexts["ctx"].Extensions))
+}
+
+func TestMarkedEntries(t *testing.T) {
+	t.Parallel()
+	clusters := cluster.GetPredictableMockClusters(3)
+	clusters[0].Provider = cluster.AWS
+	clusters[1].Provider = cluster.AWS
+	clusters[1].AccountID = "123456789012"
+	clusters[2].Provider = cluster.Google
+
+	k := New()
+	for _, cls := range clusters {
+		k.AddCluster(cls, cls.Name)
+	}
+	k.cfg.Clusters["manual"] = clusters[0].GetConfigCluster()
+
+	keys := func(entries []Entry) []string {
+		r := []string{}
+		for _, entry := range entries {
+			assert.NotNil(t, entry.Marker)
+			r = append(r, entry.Key)
+		}
+		return r
+	}
+
+	assert.Len(t, k.MarkedEntries(MarkerQuery{}), 3)
+	assert.ElementsMatch(t,
+		[]string{clusters[0].GetUniqueID(), clusters[1].GetUniqueID()},
+		keys(k.MarkedEntries(MarkerQuery{Provider: "aws"})))
+	assert.Equal(t,
+		[]string{clusters[1].GetUniqueID()},
+		keys(k.MarkedEntries(MarkerQuery{Provider: "aws", AccountID: "123456789012"})))
+	assert.Equal(t,
+		[]string{clusters[2].GetUniqueID()},
+		keys(k.MarkedEntries(MarkerQuery{ID: clusters[2].GetUniqueID(), Region: clusters[2].Region})))
+	assert.Empty(t, k.MarkedEntries(MarkerQuery{Region: "missing"}))
+
+	manual := k.Entries("manual")
+	if assert.Len(t, manual, 1) {
+		assert.Nil(t, manual[0].Marker)
+	}
+}
@@ -0,0 +1,143 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// lockSuffix is the lock file convention of client-go, kubectl
+	// takes the same lock when it modifies the kubeconfig
+	lockSuffix = ".lock"
+	dirPerm    = 0o755
+	// filePerm is used for new files, like clientcmd.WriteToFile does
+	filePerm = 0o600
+)
+
+// ErrLocked is returned when another process is writing the kubeconfig
+var ErrLocked = errors.New("kubeconfig is locked")
+
+// Lock takes the lock of the kubeconfig and returns the function that
+// releases it. The lock is taken on the path as given, like kubectl
+// does, so both tools exclude each other when the kubeconfig is a
+// symlink. Hold it from the load to the write so no concurrent change
+// is lost.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return nil, err
+	}
+	lock := path + lockSuffix
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL, 0)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf(
+			"%w: %v exists, another process is writing %v (remove the lock if no other process is running)",
+			ErrLocked, lock, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't lock %v: %w", path, err)
+	}
+	f.Close()
+	return func() { os.Remove(lock) }, nil
+}
+
+// resolvePath follows the symlinks so they are kept and the target
+// is replaced instead
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return path, nil
+	}
+	return resolved, err
+}
+
+// Persist writes the kubeconfig to the disk like WriteFile
+func (k *Kubeconfig) Persist(path string) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return k.PersistLocked(path)
+}
+
+// PersistLocked is Persist for the callers holding the lock
+func (k *Kubeconfig) PersistLocked(path string) error {
+	data, err := clientcmd.Write(*k.cfg)
+	if err != nil {
+		return err
+	}
+	return WriteLocked(path, data)
+}
+
+// WriteFile replaces the kubeconfig while holding its lock. The file is
+// written next to the target and renamed over it so readers never see
+// a partial file, the mode and the owner of the existing file are kept.
+func WriteFile(path string, data []byte) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return WriteLocked(path, data)
+}
+
+// WriteLocked is WriteFile for the callers holding the lock
+func WriteLocked(path string, data []byte) error {
+	path, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(filePerm)
+	original, err := os.Stat(path)
+	switch {
+	case err == nil:
+		mode = original.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// a no-op after the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if original != nil {
+		if err := copyOwner(tmp, original); err != nil {
+			tmp.Close()
+			return fmt.Errorf("can't keep the owner of %v: %w", path, err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
@@ -0,0 +1,147 @@
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	cluster "github.com/mateimicu/kdiscover/internal/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKubeconfig(count int) *Kubeconfig {
+	k := New()
+	for _, cls := range cluster.GetPredictableMockClusters(count) {
+		k.AddCluster(cls, cls.Name)
+	}
+	return k
+}
+
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	found := []string{}
+	for _, e := range entries {
+		found = append(found, e.Name())
+	}
+	assert.ElementsMatch(t, names, found)
+}
+
+func TestPersistNewFile(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), "missing")
+	path := filepath.Join(dir, "config")
+
+	assert.NoError(t, newTestKubeconfig(2).Persist(path))
+
+	loaded, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.Entries(""), 2)
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(filePerm), info.Mode().Perm())
+	}
+	assertOnlyFiles(t, dir, "config")
+}
+
+func TestPersistKeepsMode(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(path, []byte{}, 0o640))
+	assert.NoError(t, os.Chmod(path, 0o640))
+
+	assert.NoError(t, newTestKubeconfig(1).Persist(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	assertOnlyFiles(t, dir, "config")
+}
+
+func TestPersistLocked(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	assert.NoError(t, newTestKubeconfig(1).Persist(path))
+	original, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path+lockSuffix, []byte{}, filePerm))
+	err = newTestKubeconfig(3).Persist(path)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), path+lockSuffix)
+
+	current, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, original, current)
+	// the lock of the other process is kept
+	assertOnlyFiles(t, dir, "config", "config"+lockSuffix)
+}
+
+func TestPersistSymlink(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(target, []byte{}, filePerm))
+	assert.NoError(t, os.Symlink(target, link))
+
+	assert.NoError(t, newTestKubeconfig(1).Persist(link))
+
+	info, err := os.Lstat(link)
+	assert.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, info.Mode()&os.ModeSymlink)
+	loaded, err := LoadKubeconfig(target)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.Entries(""), 1)
+	assertOnlyFiles(t, dir, "config", "target")
+}
+
+func TestPersistSymlinkLock(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges")
+	}
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "config")
+	assert.NoError(t, os.WriteFile(target, []byte{}, filePerm))
+	assert.NoError(t, os.Symlink(target, link))
+
+	// kubectl locks the path it was given, not the target
+	assert.NoError(t, os.WriteFile(link+lockSuffix, []byte{}, filePerm))
+	assert.ErrorIs(t, newTestKubeconfig(1).Persist(link), ErrLocked)
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+
+	unlock, err := Lock(path)
+	assert.NoError(t, err)
+	// the other writers wait for the whole load-modify-write
+	assert.ErrorIs(t, newTestKubeconfig(1).Persist(path), ErrLocked)
+	_, err = Lock(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	assert.NoError(t, newTestKubeconfig(2).PersistLocked(path))
+	unlock()
+	assertOnlyFiles(t, dir, "config")
+
+	loaded, err := LoadKubeconfig(path)
+	assert.NoError(t, err)
+	assert.Len(t, loaded.Entries(""), 2)
+	assert.NoError(t, newTestKubeconfig(1).Persist(path))
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+// Package internal provides function to update kubeconfigs
+package kubeconfig
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives the new file the owner of the original one, this
+// matters when the kubeconfig is updated with sudo
+func copyOwner(f *os.File, original os.FileInfo) error {
+	stat, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if int(stat.Uid) == os.Getuid() && int(stat.Gid) == os.Getgid() {
+		return nil
+	}
+	return f.Chown(int(stat.Uid), int(stat.Gid))
+}
+
+// syncDir persists the rename
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}